@@ -0,0 +1,163 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/hbelmiro/fusectx/pkg/fusectx"
+	"github.com/spf13/cobra"
+)
+
+const cacheVersion = 1
+
+type buildCache struct {
+	mu      sync.Mutex
+	dir     string
+	Version int                   `json:"version"`
+	Options string                `json:"options"`
+	Roots   map[string]cacheEntry `json:"roots"`
+	current map[string]cacheEntry
+}
+
+type cacheEntry struct {
+	Files  map[string]string `json:"files"`
+	Output string            `json:"output"`
+}
+
+func cachePath(dir string) string {
+	return filepath.Join(dir, ".fusectx", "cache.json")
+}
+
+func newCache(dir, options string) *buildCache {
+	return &buildCache{
+		dir:     dir,
+		Version: cacheVersion,
+		Options: options,
+		Roots:   make(map[string]cacheEntry),
+		current: make(map[string]cacheEntry),
+	}
+}
+
+func loadCache(dir, options string) *buildCache {
+	cache := newCache(dir, options)
+
+	data, err := os.ReadFile(cachePath(dir))
+	if err != nil {
+		return cache
+	}
+
+	var stored buildCache
+	if err := json.Unmarshal(data, &stored); err != nil {
+		return cache
+	}
+	if stored.Version == cacheVersion && stored.Options == options && stored.Roots != nil {
+		cache.Roots = stored.Roots
+	}
+	return cache
+}
+
+func (c *buildCache) save() error {
+	c.mu.Lock()
+	c.Roots = maps.Clone(c.current)
+	data, err := json.MarshalIndent(c, "", "  ")
+	c.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	path := cachePath(c.dir)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("error creating %s: %w", filepath.Dir(path), err)
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("error writing %s: %w", path, err)
+	}
+	return nil
+}
+
+func (c *buildCache) key(path string) string {
+	rel, err := filepath.Rel(absPath(c.dir), absPath(path))
+	if err != nil {
+		return filepath.ToSlash(absPath(path))
+	}
+	return filepath.ToSlash(rel)
+}
+
+func (c *buildCache) hashGraph(r *fusectx.Resolver, root string) (map[string]string, error) {
+	graph, err := r.Graph(root)
+	if err != nil {
+		return nil, err
+	}
+
+	files := make(map[string]string, len(graph.Nodes))
+	for _, node := range graph.Nodes {
+		hash, err := hashFile(node.Path)
+		if err != nil {
+			return nil, err
+		}
+		files[c.key(node.Path)] = hash
+	}
+	return files, nil
+}
+
+func (c *buildCache) upToDate(root, output string, files map[string]string) bool {
+	c.mu.Lock()
+	entry, ok := c.Roots[c.key(root)]
+	c.mu.Unlock()
+	if !ok || !maps.Equal(entry.Files, files) {
+		return false
+	}
+	hash, err := hashFile(output)
+	if err != nil || hash != entry.Output {
+		return false
+	}
+	c.mu.Lock()
+	c.current[c.key(root)] = entry
+	c.mu.Unlock()
+	return true
+}
+
+func (c *buildCache) record(root, output string, files map[string]string) {
+	hash, err := hashFile(output)
+	if err != nil {
+		c.forget(root)
+		return
+	}
+	c.mu.Lock()
+	c.current[c.key(root)] = cacheEntry{Files: files, Output: hash}
+	c.mu.Unlock()
+}
+
+func (c *buildCache) forget(root string) {
+	c.mu.Lock()
+	delete(c.current, c.key(root))
+	c.mu.Unlock()
+}
+
+func hashFile(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+func buildFingerprint(cmd *cobra.Command) string {
+	hash := sha256.New()
+	fmt.Fprintf(hash, "version=%s\n", version)
+	for _, name := range []string{"allow-duplicates", "max-tokens", "strict", "tokenizer", "bpe-file", "profile", "tag"} {
+		fmt.Fprintf(hash, "%s=%s\n", name, cmd.Flags().Lookup(name).Value)
+	}
+	if bpeFile, _ := cmd.Flags().GetString("bpe-file"); bpeFile != "" {
+		ranks, _ := hashFile(bpeFile)
+		fmt.Fprintf(hash, "bpe-ranks=%s\n", ranks)
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
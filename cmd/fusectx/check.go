@@ -0,0 +1,50 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"strings"
+
+	"github.com/hbelmiro/fusectx/internal/diff"
+	"github.com/hbelmiro/fusectx/pkg/fusectx"
+)
+
+func checkOutputs(r *fusectx.Resolver, files []string, silent bool) int {
+	stale := 0
+	for _, file := range files {
+		outputFile := strings.TrimSuffix(file, ".md") + ".ctx"
+
+		result, err := r.Resolve(file)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to build %s: %v\n", file, err)
+			stale++
+			continue
+		}
+
+		existing, err := os.ReadFile(outputFile)
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			fmt.Fprintf(os.Stderr, "Failed to read %s: %v\n", outputFile, err)
+			stale++
+			continue
+		}
+
+		if string(existing) == result.Content {
+			if !silent {
+				fmt.Fprintf(os.Stderr, "%s is up to date\n", outputFile)
+			}
+			continue
+		}
+
+		stale++
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s is missing\n", outputFile)
+			fmt.Print(diff.Added(outputFile, outputFile, result.Content))
+			continue
+		}
+		fmt.Fprintf(os.Stderr, "%s is out of date\n", outputFile)
+		fmt.Print(diff.Unified(outputFile, outputFile, string(existing), result.Content))
+	}
+	return stale
+}
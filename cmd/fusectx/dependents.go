@@ -0,0 +1,86 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/hbelmiro/fusectx/pkg/fusectx"
+	"github.com/spf13/cobra"
+)
+
+var dependentsCmd = &cobra.Command{
+	Use:   "dependents <file> [directory]",
+	Short: "Lists the fusectx.md files that depend on a file",
+	Long: `Scans a directory for fusectx.md files and lists every one that depends on the given file, directly or transitively, through extends or includes.
+A fusectx.md file is listed as its own dependent. With --build only the listed files are rebuilt.`,
+	Args: cobra.RangeArgs(1, 2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		file := args[0]
+		targetDir := "."
+		if len(args) > 1 {
+			targetDir = args[1]
+		}
+
+		build, _ := cmd.Flags().GetBool("build")
+		silent, _ := cmd.Flags().GetBool("silent")
+
+		if _, err := os.Stat(file); err != nil {
+			return fmt.Errorf("error reading %s: %w", file, err)
+		}
+
+		opts, err := buildOptions(cmd)
+		if err != nil {
+			return err
+		}
+		r := fusectx.New(opts...)
+
+		fusectxFiles, err := findFusectxFiles(targetDir)
+		if err != nil {
+			return fmt.Errorf("failed to find fusectx files: %w", err)
+		}
+
+		graph := &fusectx.Graph{}
+		roots := make(map[string]string)
+		for _, root := range fusectxFiles {
+			rootGraph, err := r.Graph(root)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Skipping %s: %v\n", root, err)
+				continue
+			}
+			graph.Nodes = append(graph.Nodes, rootGraph.Nodes...)
+			graph.Edges = append(graph.Edges, rootGraph.Edges...)
+			roots[absPath(root)] = root
+		}
+
+		dependents := graph.Dependents(absPath(file))
+		if len(dependents) == 0 {
+			if !silent {
+				fmt.Fprintf(os.Stderr, "No fusectx.md files depend on %s\n", file)
+			}
+			return nil
+		}
+
+		failed := 0
+		for _, dependent := range dependents {
+			if !build {
+				fmt.Println(roots[dependent])
+				continue
+			}
+			if !buildOutput(r, roots[dependent], silent, os.Stdout, os.Stderr) {
+				failed++
+			}
+		}
+		if failed > 0 {
+			return fmt.Errorf("failed to build %d of %d dependents", failed, len(dependents))
+		}
+		return nil
+	},
+}
+
+func init() {
+	dependentsCmd.Flags().Bool("build", false, "Rebuild the dependent fusectx.md files instead of listing them")
+	dependentsCmd.Flags().BoolP("silent", "s", false, "Suppress output messages")
+	addBuildFlags(dependentsCmd)
+
+	rootCmd.AddCommand(dependentsCmd)
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/hbelmiro/fusectx/pkg/fusectx"
+	"github.com/spf13/cobra"
+)
+
+var graphCmd = &cobra.Command{
+	Use:   "graph <source_file|directory>",
+	Short: "Exports the dependency graph as Graphviz DOT, Mermaid or JSON",
+	Long: `Exports the dependency graph of a source file, or of every fusectx.md file in a directory, as Graphviz DOT, Mermaid or JSON.
+Nodes are files and edges are typed as extends or include.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		target := args[0]
+		format, _ := cmd.Flags().GetString("format")
+		output, _ := cmd.Flags().GetString("output")
+
+		if format != "dot" && format != "mermaid" && format != "json" {
+			return fmt.Errorf("unknown format %q (expected dot, mermaid or json)", format)
+		}
+
+		roots := []string{target}
+		if info, err := os.Stat(target); err == nil && info.IsDir() {
+			roots, err = findFusectxFiles(target)
+			if err != nil {
+				return fmt.Errorf("failed to find fusectx files: %w", err)
+			}
+		}
+
+		graph, err := fusectx.New(conditionOptions(cmd)...).Graph(roots...)
+		if err != nil {
+			return fmt.Errorf("failed to build dependency graph: %w", err)
+		}
+		for i := range graph.Nodes {
+			graph.Nodes[i].Path = displayPath(graph.Nodes[i].Path)
+		}
+		for i := range graph.Edges {
+			graph.Edges[i].From = displayPath(graph.Edges[i].From)
+			graph.Edges[i].To = displayPath(graph.Edges[i].To)
+		}
+
+		var content string
+		switch format {
+		case "mermaid":
+			content = graph.Mermaid()
+		case "json":
+			var buffer strings.Builder
+			encoder := json.NewEncoder(&buffer)
+			encoder.SetEscapeHTML(false)
+			encoder.SetIndent("", "  ")
+			if err := encoder.Encode(graph); err != nil {
+				return err
+			}
+			content = buffer.String()
+		default:
+			content = graph.DOT()
+		}
+
+		if output == "" {
+			fmt.Print(content)
+			return nil
+		}
+		if err := os.WriteFile(output, []byte(content), 0644); err != nil {
+			return fmt.Errorf("failed to write to %s: %w", output, err)
+		}
+		return nil
+	},
+}
+
+func init() {
+	graphCmd.Flags().StringP("format", "f", "dot", "Output format (dot, mermaid or json)")
+	graphCmd.Flags().StringP("output", "o", "", "Output file path")
+	addConditionFlags(graphCmd)
+
+	rootCmd.AddCommand(graphCmd)
+}
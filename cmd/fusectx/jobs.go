@@ -0,0 +1,47 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"sync"
+)
+
+type jobOutput struct {
+	stdout bytes.Buffer
+	stderr bytes.Buffer
+	done   chan struct{}
+}
+
+func runJobs(files []string, jobs int, run func(file string, stdout, stderr io.Writer)) {
+	outputs := make([]*jobOutput, len(files))
+	for i := range outputs {
+		outputs[i] = &jobOutput{done: make(chan struct{})}
+	}
+
+	queue := make(chan int)
+	var wg sync.WaitGroup
+	for range min(jobs, len(files)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range queue {
+				run(files[i], &outputs[i].stdout, &outputs[i].stderr)
+				close(outputs[i].done)
+			}
+		}()
+	}
+	go func() {
+		for i := range files {
+			queue <- i
+		}
+		close(queue)
+	}()
+
+	for _, output := range outputs {
+		<-output.done
+		os.Stdout.Write(output.stdout.Bytes())
+		os.Stderr.Write(output.stderr.Bytes())
+	}
+	wg.Wait()
+}
@@ -1,12 +1,17 @@
 package main
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"runtime"
+	"strconv"
 	"strings"
 
-	"github.com/hbelmiro/fusectx/internal/resolver"
+	"github.com/hbelmiro/fusectx/pkg/fusectx"
 	"github.com/spf13/cobra"
 )
 
@@ -28,25 +33,57 @@ var buildCmd = &cobra.Command{
 		sourceFile := args[0]
 		output, _ := cmd.Flags().GetString("output")
 		silent, _ := cmd.Flags().GetBool("silent")
+		set, _ := cmd.Flags().GetStringArray("set")
+		annotate, _ := cmd.Flags().GetString("annotate")
+		format, _ := cmd.Flags().GetString("format")
+		watchMode, _ := cmd.Flags().GetBool("watch")
 
-		content, err := resolver.Resolve(sourceFile, nil)
+		opts, err := buildOptions(cmd)
 		if err != nil {
-			return fmt.Errorf("failed to resolve %s: %w", sourceFile, err)
+			return err
+		}
+		vars, err := parseVars(set)
+		if err != nil {
+			return err
 		}
+		opts = append(opts, fusectx.WithAnnotate(annotate), fusectx.WithFormat(format), fusectx.WithVars(vars))
 
-		if output != "" {
-			err = os.WriteFile(output, []byte(content), 0644)
+		r := fusectx.New(opts...)
+
+		build := func(sourceFile string) error {
+			result, err := r.Resolve(sourceFile)
 			if err != nil {
-				return fmt.Errorf("failed to write to %s: %w", output, err)
+				return fmt.Errorf("failed to resolve %s: %w", sourceFile, err)
 			}
 			if !silent {
-				fmt.Printf("Output written to %s\n", output)
+				reportCuts(os.Stderr, result.Cuts)
 			}
-		} else {
-			fmt.Print(content)
+
+			if output != "" {
+				err = os.WriteFile(output, []byte(result.Content), 0644)
+				if err != nil {
+					return fmt.Errorf("failed to write to %s: %w", output, err)
+				}
+				if !silent {
+					fmt.Printf("Output written to %s\n", output)
+				}
+			} else {
+				fmt.Print(result.Content)
+			}
+			return nil
 		}
 
-		return nil
+		if !watchMode {
+			return build(sourceFile)
+		}
+
+		rebuild := func(sourceFile string) {
+			if err := build(sourceFile); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+			}
+		}
+		rebuild(sourceFile)
+		return watch(r, []string{sourceFile}, rebuild, silent)
 	},
 }
 
@@ -110,33 +147,61 @@ var initCmd = &cobra.Command{
 var validateCmd = &cobra.Command{
 	Use:   "validate <source_file>",
 	Short: "Checks the entire dependency chain for errors without generating an output",
-	Args:  cobra.ExactArgs(1),
+	Long: `Checks the entire dependency chain for errors without generating an output.
+Every missing file, circular dependency, invalid frontmatter and unknown frontmatter key is reported at once.`,
+	Args: cobra.ExactArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
 		sourceFile := args[0]
 		showChain, _ := cmd.Flags().GetBool("show-chain")
 		quiet, _ := cmd.Flags().GetBool("quiet")
+		set, _ := cmd.Flags().GetStringArray("set")
+		format, _ := cmd.Flags().GetString("format")
+
+		if format != "text" && format != "json" {
+			return fmt.Errorf("unknown format %q (expected text or json)", format)
+		}
 
-		err := resolver.ValidateChain(sourceFile)
+		vars, err := parseVars(set)
 		if err != nil {
-			if !quiet {
-				fmt.Fprintf(os.Stderr, "Validation failed: %v\n", err)
+			return err
+		}
+
+		r := fusectx.New(append(conditionOptions(cmd), fusectx.WithVars(vars))...)
+		report := validationReport{Valid: true, Problems: []fusectx.Problem{}}
+
+		if err := r.Validate(sourceFile); err != nil {
+			report.Valid = false
+			var validationErr *fusectx.ValidationError
+			if errors.As(err, &validationErr) {
+				report.Problems = validationErr.Problems()
+			} else {
+				report.Problems = append(report.Problems, fusectx.Problem{Kind: fusectx.ProblemError, Message: err.Error()})
 			}
-			os.Exit(1)
 		}
 
-		if showChain {
-			chain, err := resolver.GetDependencyChain(sourceFile, nil)
+		if report.Valid && showChain {
+			chain, err := r.Chain(sourceFile)
 			if err != nil {
 				return fmt.Errorf("failed to get dependency chain: %w", err)
 			}
-			fmt.Println("Dependency chain:")
-			for i, file := range chain {
-				fmt.Printf("%d. %s\n", i+1, file)
+			for _, dependency := range chain {
+				report.Chain = append(report.Chain, dependency.Path)
 			}
 		}
 
-		if !quiet {
-			fmt.Println("Validation successful")
+		if format == "json" {
+			encoder := json.NewEncoder(os.Stdout)
+			encoder.SetEscapeHTML(false)
+			encoder.SetIndent("", "  ")
+			if err := encoder.Encode(report); err != nil {
+				return err
+			}
+		} else if !quiet {
+			printValidationReport(report)
+		}
+
+		if !report.Valid {
+			os.Exit(1)
 		}
 		return nil
 	},
@@ -155,6 +220,23 @@ var buildAllCmd = &cobra.Command{
 		}
 
 		silent, _ := cmd.Flags().GetBool("silent")
+		watchMode, _ := cmd.Flags().GetBool("watch")
+		checkMode, _ := cmd.Flags().GetBool("check")
+		noCache, _ := cmd.Flags().GetBool("no-cache")
+		jobs, _ := cmd.Flags().GetInt("jobs")
+
+		if watchMode && checkMode {
+			return fmt.Errorf("--check cannot be combined with --watch")
+		}
+		if jobs < 1 {
+			return fmt.Errorf("--jobs must be at least 1")
+		}
+
+		opts, err := buildOptions(cmd)
+		if err != nil {
+			return err
+		}
+		r := fusectx.New(opts...)
 
 		fusectxFiles, err := findFusectxFiles(targetDir)
 		if err != nil {
@@ -168,29 +250,53 @@ var buildAllCmd = &cobra.Command{
 			return nil
 		}
 
-		for _, file := range fusectxFiles {
-			if !silent {
-				fmt.Printf("Building %s...\n", file)
+		if checkMode {
+			if stale := checkOutputs(r, fusectxFiles, silent); stale > 0 {
+				fmt.Fprintf(os.Stderr, "%d of %d outputs are not up to date; run fusectx build-all to regenerate them\n", stale, len(fusectxFiles))
+				os.Exit(1)
 			}
+			return nil
+		}
 
-			content, err := resolver.Resolve(file, nil)
-			if err != nil {
-				fmt.Fprintf(os.Stderr, "Failed to build %s: %v\n", file, err)
-				continue
+		cache := newCache(targetDir, buildFingerprint(cmd))
+		if !noCache {
+			cache = loadCache(targetDir, buildFingerprint(cmd))
+		}
+		saveCache := func() {
+			if err := cache.save(); err != nil {
+				fmt.Fprintf(os.Stderr, "Failed to save build cache: %v\n", err)
 			}
+		}
 
+		build := func(r *fusectx.Resolver, file string, stdout, stderr io.Writer) {
 			outputFile := strings.TrimSuffix(file, ".md") + ".ctx"
-			err = os.WriteFile(outputFile, []byte(content), 0644)
-			if err != nil {
-				fmt.Fprintf(os.Stderr, "Failed to write output for %s: %v\n", file, err)
-				continue
+			files, err := cache.hashGraph(r, file)
+			if err == nil && cache.upToDate(file, outputFile, files) {
+				if !silent {
+					fmt.Fprintf(stdout, "Skipping %s, %s is up to date\n", file, outputFile)
+				}
+				return
 			}
-
-			if !silent {
-				fmt.Printf("Output written to %s\n", outputFile)
+			if !buildOutput(r, file, silent, stdout, stderr) || err != nil {
+				cache.forget(file)
+				return
 			}
+			cache.record(file, outputFile, files)
 		}
 
+		shared := fusectx.New(append(opts, fusectx.WithParseCache(true))...)
+		runJobs(fusectxFiles, jobs, func(file string, stdout, stderr io.Writer) {
+			build(shared, file, stdout, stderr)
+		})
+		saveCache()
+
+		if watchMode {
+			rebuild := func(file string) {
+				build(r, file, os.Stdout, os.Stderr)
+				saveCache()
+			}
+			return watch(r, fusectxFiles, rebuild, silent)
+		}
 		return nil
 	},
 }
@@ -308,6 +414,137 @@ var cleanAllCmd = &cobra.Command{
 	},
 }
 
+func buildOutput(r *fusectx.Resolver, file string, silent bool, stdout, stderr io.Writer) bool {
+	if !silent {
+		fmt.Fprintf(stdout, "Building %s...\n", file)
+	}
+
+	result, err := r.Resolve(file)
+	if err != nil {
+		fmt.Fprintf(stderr, "Failed to build %s: %v\n", file, err)
+		return false
+	}
+	if !silent {
+		reportCuts(stderr, result.Cuts)
+	}
+
+	outputFile := strings.TrimSuffix(file, ".md") + ".ctx"
+	if existing, err := os.ReadFile(outputFile); err == nil && string(existing) == result.Content {
+		if !silent {
+			fmt.Fprintf(stdout, "Output %s is unchanged\n", outputFile)
+		}
+		return true
+	}
+
+	err = os.WriteFile(outputFile, []byte(result.Content), 0644)
+	if err != nil {
+		fmt.Fprintf(stderr, "Failed to write output for %s: %v\n", file, err)
+		return false
+	}
+
+	if !silent {
+		fmt.Fprintf(stdout, "Output written to %s\n", outputFile)
+	}
+	return true
+}
+
+func addBuildFlags(cmd *cobra.Command) {
+	cmd.Flags().Bool("allow-duplicates", false, "Emit files reached through several paths more than once")
+	cmd.Flags().Int("max-tokens", 0, "Token budget for the output, overriding max_tokens from the frontmatter")
+	cmd.Flags().Bool("strict", false, "Fail instead of trimming includes when the output exceeds the token budget")
+	cmd.Flags().String("tokenizer", "chars", "Tokenizer used to enforce the token budget (chars or bpe)")
+	cmd.Flags().String("bpe-file", "", "Path to a tiktoken rank file for the bpe tokenizer (default: embedded cl100k_base)")
+	addConditionFlags(cmd)
+}
+
+func addConditionFlags(cmd *cobra.Command) {
+	cmd.Flags().String("profile", "", "Active profile for includes with a when condition")
+	cmd.Flags().StringSlice("tag", nil, "Active tag for includes with a when condition (repeatable)")
+}
+
+func conditionOptions(cmd *cobra.Command) []fusectx.Option {
+	profile, _ := cmd.Flags().GetString("profile")
+	tags, _ := cmd.Flags().GetStringSlice("tag")
+	return []fusectx.Option{fusectx.WithProfile(profile), fusectx.WithTags(tags...)}
+}
+
+func buildOptions(cmd *cobra.Command) ([]fusectx.Option, error) {
+	allowDuplicates, _ := cmd.Flags().GetBool("allow-duplicates")
+	maxTokens, _ := cmd.Flags().GetInt("max-tokens")
+	strict, _ := cmd.Flags().GetBool("strict")
+	tokenizerName, _ := cmd.Flags().GetString("tokenizer")
+	bpeFile, _ := cmd.Flags().GetString("bpe-file")
+
+	tok, err := fusectx.NewTokenizer(tokenizerName, bpeFile)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := []fusectx.Option{
+		fusectx.WithDedup(!allowDuplicates),
+		fusectx.WithMaxTokens(maxTokens),
+		fusectx.WithStrict(strict),
+		fusectx.WithTokenizer(tok),
+	}
+	return append(opts, conditionOptions(cmd)...), nil
+}
+
+type validationReport struct {
+	Valid    bool              `json:"valid"`
+	Problems []fusectx.Problem `json:"problems"`
+	Chain    []string          `json:"chain,omitempty"`
+}
+
+func printValidationReport(report validationReport) {
+	if !report.Valid {
+		fmt.Fprintf(os.Stderr, "Validation failed with %d problem(s):\n", len(report.Problems))
+		for _, problem := range report.Problems {
+			fmt.Fprintf(os.Stderr, "  %s\n", formatProblem(problem))
+		}
+		return
+	}
+
+	if len(report.Chain) > 0 {
+		fmt.Println("Dependency chain:")
+		for i, file := range report.Chain {
+			fmt.Printf("%d. %s\n", i+1, file)
+		}
+	}
+	fmt.Println("Validation successful")
+}
+
+func formatProblem(problem fusectx.Problem) string {
+	location := problem.Path
+	if location != "" && problem.Line > 0 {
+		location += ":" + strconv.Itoa(problem.Line)
+		if problem.Column > 0 {
+			location += ":" + strconv.Itoa(problem.Column)
+		}
+	}
+	if location == "" {
+		return problem.Message
+	}
+	return location + ": " + problem.Message
+}
+
+func reportCuts(w io.Writer, cuts []fusectx.Cut) {
+	for _, cut := range cuts {
+		fmt.Fprintf(w, "Token budget exceeded, %s\n", cut)
+	}
+}
+
+func parseVars(values []string) (map[string]string, error) {
+	vars := make(map[string]string)
+	for _, value := range values {
+		key, val, ok := strings.Cut(value, "=")
+		if !ok || key == "" {
+			return nil, fmt.Errorf("invalid --set value %q, expected key=value", value)
+		}
+		vars[key] = val
+	}
+	return vars, nil
+}
+
 func findFusectxFiles(dir string) ([]string, error) {
 	var files []string
 
@@ -347,6 +584,12 @@ func findCtxFiles(dir string) ([]string, error) {
 func init() {
 	buildCmd.Flags().StringP("output", "o", "", "Output file path")
 	buildCmd.Flags().BoolP("silent", "s", false, "Suppress output messages")
+	buildCmd.Flags().StringArray("set", nil, "Set a template variable (key=value), overriding frontmatter vars")
+	buildCmd.Flags().String("annotate", "", "Mark the source of each file's content (html, xml or heading)")
+	buildCmd.Flags().Lookup("annotate").NoOptDefVal = fusectx.AnnotateHTML
+	buildCmd.Flags().String("format", fusectx.FormatText, "Output format (text, xml or json)")
+	buildCmd.Flags().BoolP("watch", "w", false, "Rebuild whenever a file in the dependency chain changes")
+	addBuildFlags(buildCmd)
 
 	initCmd.Flags().StringP("extends", "e", "", "Set extends path")
 	initCmd.Flags().StringSliceP("includes", "i", nil, "Set includes paths")
@@ -354,8 +597,16 @@ func init() {
 
 	validateCmd.Flags().Bool("show-chain", false, "Show the dependency chain")
 	validateCmd.Flags().BoolP("quiet", "q", false, "Suppress output messages")
+	validateCmd.Flags().StringArray("set", nil, "Set a template variable (key=value), overriding frontmatter vars")
+	validateCmd.Flags().StringP("format", "f", "text", "Output format (text or json)")
+	addConditionFlags(validateCmd)
 
 	buildAllCmd.Flags().BoolP("silent", "s", false, "Suppress output messages")
+	buildAllCmd.Flags().BoolP("watch", "w", false, "Rebuild outputs whenever a file in their dependency chain changes")
+	buildAllCmd.Flags().Bool("check", false, "Verify that existing outputs are up to date without writing them, printing a diff for each stale one")
+	buildAllCmd.Flags().Bool("no-cache", false, "Rebuild every output, ignoring the build cache")
+	buildAllCmd.Flags().IntP("jobs", "j", runtime.NumCPU(), "Number of files to build concurrently")
+	addBuildFlags(buildAllCmd)
 
 	cleanCmd.Flags().StringP("output", "o", "", "Output file path (must match the -o flag used with build)")
 	cleanCmd.Flags().BoolP("dry-run", "d", false, "Show what would be removed without actually removing files")
@@ -1,11 +1,14 @@
 package main
 
 import (
+	"encoding/json"
+	"fmt"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 )
 
 func TestCLICommands(t *testing.T) {
@@ -87,6 +90,716 @@ Main content`
 		}
 	})
 
+	t.Run("build with vars", func(t *testing.T) {
+		content := `---
+vars:
+  service: default
+  port: 80
+---
+# {{ .service }}
+Port {{ .port }}`
+		err := os.WriteFile("service.md", []byte(content), 0644)
+		if err != nil {
+			t.Fatalf("failed to write service file: %v", err)
+		}
+
+		cmd := exec.Command(binaryPath, "build", "service.md", "--set", "service=billing")
+		output, err := cmd.Output()
+		if err != nil {
+			t.Fatalf("build command failed: %v", err)
+		}
+
+		expected := "# billing\nPort 80"
+		if strings.TrimSpace(string(output)) != expected {
+			t.Errorf("expected:\n%s\n\ngot:\n%s", expected, string(output))
+		}
+
+		cmd = exec.Command(binaryPath, "build", "service.md", "--set", "service")
+		if err := cmd.Run(); err == nil {
+			t.Error("expected build to fail for malformed --set value")
+		}
+	})
+
+	t.Run("validate undefined variable", func(t *testing.T) {
+		err := os.WriteFile("undefined.md", []byte("---\nvars:\n  team: core\n---\n# {{ .service }}"), 0644)
+		if err != nil {
+			t.Fatalf("failed to write test file: %v", err)
+		}
+
+		cmd := exec.Command(binaryPath, "validate", "undefined.md")
+		output, err := cmd.CombinedOutput()
+		if err == nil {
+			t.Fatalf("expected validation to fail, got: %s", string(output))
+		}
+
+		if !strings.Contains(string(output), "service") {
+			t.Errorf("expected undefined variable in output, got: %s", string(output))
+		}
+
+		cmd = exec.Command(binaryPath, "validate", "undefined.md", "--set", "service=billing")
+		output, err = cmd.CombinedOutput()
+		if err != nil {
+			t.Fatalf("validate with --set failed: %v\nOutput: %s", err, string(output))
+		}
+	})
+
+	t.Run("build with annotations", func(t *testing.T) {
+		cmd := exec.Command(binaryPath, "build", "main.md", "--annotate")
+		output, err := cmd.Output()
+		if err != nil {
+			t.Fatalf("build --annotate failed: %v", err)
+		}
+
+		expected := "<!-- from: base.md -->\n# Base\nBase content\n<!-- end: base.md -->\n\n<!-- from: main.md -->\n# Main\nMain content\n<!-- end: main.md -->"
+		if strings.TrimSpace(string(output)) != expected {
+			t.Errorf("expected:\n%s\n\ngot:\n%s", expected, string(output))
+		}
+
+		cmd = exec.Command(binaryPath, "build", "main.md", "--annotate=xml")
+		output, err = cmd.Output()
+		if err != nil {
+			t.Fatalf("build --annotate=xml failed: %v", err)
+		}
+
+		if !strings.Contains(string(output), `<file path="base.md">`) {
+			t.Errorf("expected XML annotations, got:\n%s", string(output))
+		}
+	})
+
+	t.Run("build with xml format", func(t *testing.T) {
+		cmd := exec.Command(binaryPath, "build", "main.md", "--format", "xml")
+		output, err := cmd.Output()
+		if err != nil {
+			t.Fatalf("build --format xml failed: %v", err)
+		}
+
+		outputStr := string(output)
+		for _, expected := range []string{
+			"<documents>",
+			`<document index="1">`,
+			"<source>base.md</source>",
+			"<document_content>\n# Base\nBase content\n</document_content>",
+			`<document index="2">`,
+			"<source>main.md</source>",
+		} {
+			if !strings.Contains(outputStr, expected) {
+				t.Errorf("expected %q in output, got:\n%s", expected, outputStr)
+			}
+		}
+
+		cmd = exec.Command(binaryPath, "build", "main.md", "--format", "xml", "--annotate")
+		if err := cmd.Run(); err == nil {
+			t.Error("expected --annotate to be rejected with --format xml")
+		}
+	})
+
+	t.Run("build with json format", func(t *testing.T) {
+		cmd := exec.Command(binaryPath, "build", "main.md", "--format", "json")
+		output, err := cmd.Output()
+		if err != nil {
+			t.Fatalf("build --format json failed: %v", err)
+		}
+
+		var document struct {
+			Source   string `json:"source"`
+			Content  string `json:"content"`
+			Segments []struct {
+				Path     string `json:"path"`
+				Relation string `json:"relation"`
+				Depth    int    `json:"depth"`
+				Start    int    `json:"start"`
+				End      int    `json:"end"`
+				Content  string `json:"content"`
+			} `json:"segments"`
+		}
+		if err := json.Unmarshal(output, &document); err != nil {
+			t.Fatalf("invalid JSON output: %v\n%s", err, output)
+		}
+
+		if document.Source != "main.md" || len(document.Segments) != 2 {
+			t.Fatalf("unexpected document: %+v", document)
+		}
+		if document.Segments[0].Path != "base.md" || document.Segments[0].Relation != "extends" || document.Segments[0].Depth != 1 {
+			t.Errorf("unexpected first segment: %+v", document.Segments[0])
+		}
+		for _, seg := range document.Segments {
+			if document.Content[seg.Start:seg.End] != seg.Content {
+				t.Errorf("offsets %d-%d of %s do not match its content", seg.Start, seg.End, seg.Path)
+			}
+		}
+	})
+
+	t.Run("build with token budget", func(t *testing.T) {
+		err := os.WriteFile("budget-extra.md", []byte("# Extra\nBackground material that does not fit."), 0644)
+		if err != nil {
+			t.Fatalf("failed to write include file: %v", err)
+		}
+		err = os.WriteFile("budget.md", []byte("---\nincludes:\n  - path: budget-extra.md\n    priority: -1\n---\n# Budget"), 0644)
+		if err != nil {
+			t.Fatalf("failed to write budget file: %v", err)
+		}
+
+		cmd := exec.Command(binaryPath, "build", "budget.md", "--max-tokens", "5")
+		var stderr strings.Builder
+		cmd.Stderr = &stderr
+		output, err := cmd.Output()
+		if err != nil {
+			t.Fatalf("build command failed: %v\nStderr: %s", err, stderr.String())
+		}
+
+		if strings.TrimSpace(string(output)) != "# Budget" {
+			t.Errorf("expected trimmed output, got %q", string(output))
+		}
+		if !strings.Contains(stderr.String(), "dropped") || !strings.Contains(stderr.String(), "budget-extra.md") {
+			t.Errorf("expected report of dropped include, got: %s", stderr.String())
+		}
+
+		cmd = exec.Command(binaryPath, "build", "budget.md", "--max-tokens", "5", "--strict")
+		if err := cmd.Run(); err == nil {
+			t.Error("expected strict build to fail when over budget")
+		}
+	})
+
+	t.Run("build with profile and tags", func(t *testing.T) {
+		err := os.WriteFile("profile-gpu.md", []byte("# GPU"), 0644)
+		if err != nil {
+			t.Fatalf("failed to write include file: %v", err)
+		}
+		err = os.WriteFile("profile-review.md", []byte("# Review"), 0644)
+		if err != nil {
+			t.Fatalf("failed to write include file: %v", err)
+		}
+		err = os.WriteFile("profile.md", []byte("---\nincludes:\n  - path: profile-gpu.md\n    when: \"profile == 'ml'\"\n  - path: profile-review.md\n    when: {tag: review}\n---\n# Profile"), 0644)
+		if err != nil {
+			t.Fatalf("failed to write profile file: %v", err)
+		}
+
+		tests := []struct {
+			args     []string
+			expected string
+		}{
+			{expected: "# Profile"},
+			{args: []string{"--profile", "ml"}, expected: "# GPU\n\n# Profile"},
+			{args: []string{"--profile", "ml", "--tag", "review"}, expected: "# GPU\n\n# Review\n\n# Profile"},
+		}
+
+		for _, tt := range tests {
+			cmd := exec.Command(binaryPath, append([]string{"build", "profile.md"}, tt.args...)...)
+			output, err := cmd.Output()
+			if err != nil {
+				t.Fatalf("build %v failed: %v", tt.args, err)
+			}
+			if strings.TrimSpace(string(output)) != tt.expected {
+				t.Errorf("build %v: expected %q, got %q", tt.args, tt.expected, string(output))
+			}
+		}
+
+		cmd := exec.Command(binaryPath, "validate", "profile.md", "--show-chain", "--tag", "review")
+		output, err := cmd.Output()
+		if err != nil {
+			t.Fatalf("validate failed: %v", err)
+		}
+		if !strings.Contains(string(output), "profile-review.md") || strings.Contains(string(output), "profile-gpu.md") {
+			t.Errorf("expected chain to follow the active tags, got:\n%s", output)
+		}
+	})
+
+	t.Run("watch mode", func(t *testing.T) {
+		watchDir, err := os.MkdirTemp("", "fusectx-watch-test")
+		if err != nil {
+			t.Fatalf("failed to create temp dir: %v", err)
+		}
+		defer os.RemoveAll(watchDir)
+
+		files := map[string]string{
+			"shared/base.md":  "# Base v1",
+			"shared/extra.md": "# Extra v1",
+			"a/fusectx.md":    "---\nextends: ../shared/base.md\n---\n# A",
+			"b/fusectx.md":    "# B",
+		}
+		write := func(name, content string) {
+			path := filepath.Join(watchDir, name)
+			if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+				t.Fatalf("failed to create directory: %v", err)
+			}
+			if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+				t.Fatalf("failed to write %s: %v", name, err)
+			}
+		}
+		waitFor := func(name, expected string) {
+			deadline := time.Now().Add(10 * time.Second)
+			for {
+				data, _ := os.ReadFile(filepath.Join(watchDir, name))
+				if string(data) == expected {
+					return
+				}
+				if time.Now().After(deadline) {
+					t.Fatalf("timed out waiting for %s to be %q, got %q", name, expected, string(data))
+				}
+				time.Sleep(50 * time.Millisecond)
+			}
+		}
+		for name, content := range files {
+			write(name, content)
+		}
+
+		cmd := exec.Command(binaryPath, "build-all", watchDir, "--watch", "--silent")
+		if err := cmd.Start(); err != nil {
+			t.Fatalf("failed to start build-all --watch: %v", err)
+		}
+		defer func() {
+			cmd.Process.Kill()
+			cmd.Wait()
+		}()
+
+		waitFor("a/fusectx.ctx", "# Base v1\n\n# A")
+		waitFor("b/fusectx.ctx", "# B")
+
+		write("shared/base.md", "# Base v2")
+		waitFor("a/fusectx.ctx", "# Base v2\n\n# A")
+
+		write("b/fusectx.md", "---\nincludes:\n  - ../shared/extra.md\n---\n# B")
+		waitFor("b/fusectx.ctx", "# Extra v1\n\n# B")
+
+		write("shared/extra.md", "# Extra v2")
+		waitFor("b/fusectx.ctx", "# Extra v2\n\n# B")
+
+		single := exec.Command(binaryPath, "build", filepath.Join(watchDir, "a/fusectx.md"), "-o", filepath.Join(watchDir, "a.txt"), "--watch", "--silent")
+		if err := single.Start(); err != nil {
+			t.Fatalf("failed to start build --watch: %v", err)
+		}
+		defer func() {
+			single.Process.Kill()
+			single.Wait()
+		}()
+
+		waitFor("a.txt", "# Base v2\n\n# A")
+		write("shared/base.md", "# Base v3")
+		waitFor("a.txt", "# Base v3\n\n# A")
+	})
+
+	t.Run("build-all with cache", func(t *testing.T) {
+		cacheDir, err := os.MkdirTemp("", "fusectx-cache-test")
+		if err != nil {
+			t.Fatalf("failed to create temp dir: %v", err)
+		}
+		defer os.RemoveAll(cacheDir)
+
+		files := map[string]string{
+			"shared.md":    "# Shared",
+			"a/fusectx.md": "---\nextends: ../shared.md\n---\n# A",
+			"b/fusectx.md": "# B",
+		}
+		for name, content := range files {
+			path := filepath.Join(cacheDir, name)
+			if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+				t.Fatalf("failed to create directory for %s: %v", name, err)
+			}
+			if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+				t.Fatalf("failed to write %s: %v", name, err)
+			}
+		}
+
+		buildAll := func(args ...string) string {
+			output, err := exec.Command(binaryPath, append([]string{"build-all", cacheDir}, args...)...).CombinedOutput()
+			if err != nil {
+				t.Fatalf("build-all failed: %v\n%s", err, output)
+			}
+			return string(output)
+		}
+
+		buildAll()
+		if _, err := os.Stat(filepath.Join(cacheDir, ".fusectx", "cache.json")); err != nil {
+			t.Fatalf("expected a build cache: %v", err)
+		}
+
+		past := time.Now().Add(-time.Hour).Truncate(time.Second)
+		for _, name := range []string{"a/fusectx.ctx", "b/fusectx.ctx"} {
+			if err := os.Chtimes(filepath.Join(cacheDir, name), past, past); err != nil {
+				t.Fatalf("failed to set times of %s: %v", name, err)
+			}
+		}
+		modified := func(name string) bool {
+			info, err := os.Stat(filepath.Join(cacheDir, name))
+			if err != nil {
+				t.Fatalf("failed to stat %s: %v", name, err)
+			}
+			return !info.ModTime().Equal(past)
+		}
+
+		output := buildAll()
+		if strings.Contains(output, "Building") || strings.Count(output, "Skipping") != 2 {
+			t.Errorf("expected every root to be skipped, got:\n%s", output)
+		}
+
+		if err := os.WriteFile(filepath.Join(cacheDir, "shared.md"), []byte("# Shared v2"), 0644); err != nil {
+			t.Fatalf("failed to write shared.md: %v", err)
+		}
+		output = buildAll()
+		if !strings.Contains(output, "Building "+filepath.Join(cacheDir, "a/fusectx.md")) || strings.Contains(output, "Building "+filepath.Join(cacheDir, "b/fusectx.md")) {
+			t.Errorf("expected only a/fusectx.md to be rebuilt, got:\n%s", output)
+		}
+		if !modified("a/fusectx.ctx") || modified("b/fusectx.ctx") {
+			t.Error("expected only a/fusectx.ctx to be rewritten")
+		}
+
+		output = buildAll("--no-cache")
+		if strings.Count(output, "Building") != 2 || strings.Count(output, "is unchanged") != 2 {
+			t.Errorf("expected --no-cache to rebuild every root without rewriting outputs, got:\n%s", output)
+		}
+		if modified("b/fusectx.ctx") {
+			t.Error("expected an unchanged output not to be rewritten")
+		}
+
+		rankFile := filepath.Join(cacheDir, "ranks.tiktoken")
+		if err := os.WriteFile(rankFile, []byte("IA== 0\n"), 0644); err != nil {
+			t.Fatalf("failed to write rank file: %v", err)
+		}
+		buildAll("--tokenizer", "bpe", "--bpe-file", rankFile)
+		output = buildAll("--tokenizer", "bpe", "--bpe-file", rankFile)
+		if strings.Count(output, "Skipping") != 2 {
+			t.Errorf("expected every root to be skipped with the same rank file, got:\n%s", output)
+		}
+		if err := os.WriteFile(rankFile, []byte("IA== 0\nIyA= 1\n"), 0644); err != nil {
+			t.Fatalf("failed to write rank file: %v", err)
+		}
+		output = buildAll("--tokenizer", "bpe", "--bpe-file", rankFile)
+		if strings.Count(output, "Building") != 2 {
+			t.Errorf("expected a changed rank file to rebuild every root, got:\n%s", output)
+		}
+	})
+
+	t.Run("build-all with jobs", func(t *testing.T) {
+		jobsDir, err := os.MkdirTemp("", "fusectx-jobs-test")
+		if err != nil {
+			t.Fatalf("failed to create temp dir: %v", err)
+		}
+		defer os.RemoveAll(jobsDir)
+
+		if err := os.WriteFile(filepath.Join(jobsDir, "shared.md"), []byte("# Shared"), 0644); err != nil {
+			t.Fatalf("failed to write shared.md: %v", err)
+		}
+		for i := range 12 {
+			content := fmt.Sprintf("---\nextends: ../shared.md\n---\n# Project %d", i)
+			if i%4 == 0 {
+				content = fmt.Sprintf("---\nextends: ../missing.md\n---\n# Project %d", i)
+			}
+			dir := filepath.Join(jobsDir, fmt.Sprintf("project%02d", i))
+			if err := os.MkdirAll(dir, 0755); err != nil {
+				t.Fatalf("failed to create %s: %v", dir, err)
+			}
+			if err := os.WriteFile(filepath.Join(dir, "fusectx.md"), []byte(content), 0644); err != nil {
+				t.Fatalf("failed to write fusectx.md in %s: %v", dir, err)
+			}
+		}
+
+		buildAll := func(jobs string) (string, string) {
+			cmd := exec.Command(binaryPath, "build-all", jobsDir, "--no-cache", "-j", jobs)
+			var stderr strings.Builder
+			cmd.Stderr = &stderr
+			stdout, err := cmd.Output()
+			if err != nil {
+				t.Fatalf("build-all -j %s failed: %v\n%s", jobs, err, stderr.String())
+			}
+			return string(stdout), stderr.String()
+		}
+
+		buildAll("8")
+		sequentialOut, sequentialErr := buildAll("1")
+		for range 3 {
+			parallelOut, parallelErr := buildAll("8")
+			if parallelOut != sequentialOut {
+				t.Errorf("expected the same progress output as a sequential build:\n%s\ngot:\n%s", sequentialOut, parallelOut)
+			}
+			if parallelErr != sequentialErr {
+				t.Errorf("expected the same errors as a sequential build:\n%s\ngot:\n%s", sequentialErr, parallelErr)
+			}
+		}
+		if strings.Count(sequentialErr, "Failed to build") != 3 {
+			t.Errorf("expected 3 failures, got:\n%s", sequentialErr)
+		}
+		if data, err := os.ReadFile(filepath.Join(jobsDir, "project11", "fusectx.ctx")); err != nil || string(data) != "# Shared\n\n# Project 11" {
+			t.Errorf("unexpected output %q: %v", data, err)
+		}
+
+		if output, err := exec.Command(binaryPath, "build-all", jobsDir, "-j", "0").CombinedOutput(); err == nil {
+			t.Errorf("expected -j 0 to fail, got:\n%s", output)
+		}
+	})
+
+	t.Run("build-all with check", func(t *testing.T) {
+		checkDir, err := os.MkdirTemp("", "fusectx-check-test")
+		if err != nil {
+			t.Fatalf("failed to create temp dir: %v", err)
+		}
+		defer os.RemoveAll(checkDir)
+
+		source := filepath.Join(checkDir, "fusectx.md")
+		output := filepath.Join(checkDir, "fusectx.ctx")
+		if err := os.WriteFile(source, []byte("# Title\nOld line"), 0644); err != nil {
+			t.Fatalf("failed to write source file: %v", err)
+		}
+
+		if err := exec.Command(binaryPath, "build-all", checkDir, "-s").Run(); err != nil {
+			t.Fatalf("build-all failed: %v", err)
+		}
+		if output, err := exec.Command(binaryPath, "build-all", checkDir, "--check").CombinedOutput(); err != nil {
+			t.Fatalf("expected check to pass, got %v:\n%s", err, output)
+		}
+
+		if err := os.WriteFile(source, []byte("# Title\nNew line"), 0644); err != nil {
+			t.Fatalf("failed to write source file: %v", err)
+		}
+
+		cmd := exec.Command(binaryPath, "build-all", checkDir, "--check")
+		var stderr strings.Builder
+		cmd.Stderr = &stderr
+		stdout, err := cmd.Output()
+		if err == nil {
+			t.Fatal("expected check to fail for a stale output")
+		}
+		expected := "--- " + output + "\n+++ " + output + "\n@@ -1,2 +1,2 @@\n # Title\n-Old line\n\\ No newline at end of file\n+New line\n\\ No newline at end of file\n"
+		if string(stdout) != expected {
+			t.Errorf("expected diff:\n%s\ngot:\n%s", expected, stdout)
+		}
+		if !strings.Contains(stderr.String(), "1 of 1 outputs are not up to date") {
+			t.Errorf("expected a summary, got: %s", stderr.String())
+		}
+		if data, _ := os.ReadFile(output); string(data) != "# Title\nOld line" {
+			t.Errorf("expected check not to write the output, got %q", string(data))
+		}
+
+		os.Remove(output)
+		cmd = exec.Command(binaryPath, "build-all", checkDir, "--check")
+		cmd.Stderr = &stderr
+		if err := cmd.Run(); err == nil || !strings.Contains(stderr.String(), "is missing") {
+			t.Errorf("expected check to fail for a missing output, got %v: %s", err, stderr.String())
+		}
+	})
+
+	t.Run("graph command", func(t *testing.T) {
+		cmd := exec.Command(binaryPath, "graph", "main.md")
+		output, err := cmd.Output()
+		if err != nil {
+			t.Fatalf("graph command failed: %v", err)
+		}
+		if !strings.Contains(string(output), `"main.md" -> "base.md" [label="extends"];`) {
+			t.Errorf("expected an extends edge in DOT output, got:\n%s", output)
+		}
+
+		cmd = exec.Command(binaryPath, "graph", "main.md", "--format", "mermaid")
+		output, err = cmd.Output()
+		if err != nil {
+			t.Fatalf("graph --format mermaid failed: %v", err)
+		}
+		if !strings.HasPrefix(string(output), "flowchart TD\n") || !strings.Contains(string(output), `n0 -->|"extends"| n1`) {
+			t.Errorf("unexpected Mermaid output:\n%s", output)
+		}
+
+		cmd = exec.Command(binaryPath, "graph", "main.md", "--format", "json")
+		output, err = cmd.Output()
+		if err != nil {
+			t.Fatalf("graph --format json failed: %v", err)
+		}
+		var graph struct {
+			Nodes []struct {
+				Path string `json:"path"`
+				Root bool   `json:"root"`
+			} `json:"nodes"`
+			Edges []struct {
+				From     string `json:"from"`
+				To       string `json:"to"`
+				Relation string `json:"relation"`
+			} `json:"edges"`
+		}
+		if err := json.Unmarshal(output, &graph); err != nil {
+			t.Fatalf("invalid JSON output: %v\n%s", err, output)
+		}
+		if len(graph.Nodes) != 2 || !graph.Nodes[0].Root || len(graph.Edges) != 1 || graph.Edges[0].Relation != "extends" {
+			t.Errorf("unexpected graph: %+v", graph)
+		}
+
+		if err := exec.Command(binaryPath, "graph", "main.md", "--format", "svg").Run(); err == nil {
+			t.Error("expected an unknown format to be rejected")
+		}
+	})
+
+	t.Run("tree command", func(t *testing.T) {
+		files := map[string]string{
+			"tree-base.md":   "# Base\nline two",
+			"tree-shared.md": "---\nincludes:\n  - tree-base.md\n  - tree-main.md\n---\n# Shared",
+			"tree-guide.md":  "# Guide\n\n## Usage\n\nRun it.",
+			"tree-main.md":   "---\nextends: tree-base.md\nincludes:\n  - tree-shared.md\n  - tree-guide.md#usage\n---\n# Main",
+		}
+		for name, content := range files {
+			if err := os.WriteFile(name, []byte(content), 0644); err != nil {
+				t.Fatalf("failed to write %s: %v", name, err)
+			}
+		}
+
+		cmd := exec.Command(binaryPath, "tree", "tree-main.md")
+		output, err := cmd.Output()
+		if err != nil {
+			t.Fatalf("tree command failed: %v", err)
+		}
+		expected := `tree-main.md (1 line, 2 tokens)
+├── extends tree-base.md (2 lines, 4 tokens)
+├── include tree-shared.md (1 line, 2 tokens)
+│   ├── include tree-base.md (2 lines, 4 tokens) [duplicate]
+│   └── include tree-main.md (1 line, 2 tokens) [cycle]
+└── include tree-guide.md#usage (3 lines, 5 tokens)
+`
+		if string(output) != expected {
+			t.Errorf("expected:\n%s\ngot:\n%s", expected, output)
+		}
+
+		cmd = exec.Command(binaryPath, "tree", "tree-main.md", "--depth", "1")
+		output, err = cmd.Output()
+		if err != nil {
+			t.Fatalf("tree --depth failed: %v", err)
+		}
+		if strings.Contains(string(output), "│") || strings.Count(string(output), "\n") != 4 {
+			t.Errorf("expected only direct dependencies, got:\n%s", output)
+		}
+	})
+
+	t.Run("dependents command", func(t *testing.T) {
+		dependentsDir, err := os.MkdirTemp("", "fusectx-dependents-test")
+		if err != nil {
+			t.Fatalf("failed to create temp dir: %v", err)
+		}
+		defer os.RemoveAll(dependentsDir)
+
+		files := map[string]string{
+			"shared/rules.md":  "# Rules",
+			"shared/policy.md": "---\nincludes:\n  - rules.md\n---\n# Policy",
+			"a/fusectx.md":     "---\nextends: ../shared/policy.md\n---\n# A",
+			"b/fusectx.md":     "---\nincludes:\n  - ../shared/rules.md\n---\n# B",
+			"c/fusectx.md":     "# C",
+		}
+		for name, content := range files {
+			path := filepath.Join(dependentsDir, name)
+			if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+				t.Fatalf("failed to create directory for %s: %v", name, err)
+			}
+			if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+				t.Fatalf("failed to write %s: %v", name, err)
+			}
+		}
+
+		cmd := exec.Command(binaryPath, "dependents", filepath.Join(dependentsDir, "shared/rules.md"), dependentsDir)
+		output, err := cmd.Output()
+		if err != nil {
+			t.Fatalf("dependents command failed: %v", err)
+		}
+		expected := filepath.Join(dependentsDir, "a/fusectx.md") + "\n" + filepath.Join(dependentsDir, "b/fusectx.md") + "\n"
+		if string(output) != expected {
+			t.Errorf("expected:\n%s\ngot:\n%s", expected, output)
+		}
+
+		cmd = exec.Command(binaryPath, "dependents", filepath.Join(dependentsDir, "shared/policy.md"), dependentsDir, "--build", "-s")
+		if output, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("dependents --build failed: %v\n%s", err, output)
+		}
+		if _, err := os.Stat(filepath.Join(dependentsDir, "a/fusectx.ctx")); err != nil {
+			t.Errorf("expected a/fusectx.ctx to be built: %v", err)
+		}
+		for _, name := range []string{"b/fusectx.ctx", "c/fusectx.ctx"} {
+			if _, err := os.Stat(filepath.Join(dependentsDir, name)); !os.IsNotExist(err) {
+				t.Errorf("expected %s not to be built", name)
+			}
+		}
+
+		if err := os.WriteFile(filepath.Join(dependentsDir, "b/fusectx.md"), []byte("---\nincludes:\n  - ../shared/rules.md\n---\n# {{ .undefined }}\n{{ .team }}"), 0644); err != nil {
+			t.Fatalf("failed to write b/fusectx.md: %v", err)
+		}
+		if err := os.WriteFile(filepath.Join(dependentsDir, "shared/rules.md"), []byte("---\nvars:\n  team: core\n---\n# Rules"), 0644); err != nil {
+			t.Fatalf("failed to write shared/rules.md: %v", err)
+		}
+		cmd = exec.Command(binaryPath, "dependents", filepath.Join(dependentsDir, "shared/rules.md"), dependentsDir, "--build", "-s")
+		if output, err := cmd.CombinedOutput(); err == nil || !strings.Contains(string(output), "failed to build 1 of 2 dependents") {
+			t.Errorf("expected dependents --build to fail, got %v:\n%s", err, output)
+		}
+	})
+
+	t.Run("stats command", func(t *testing.T) {
+		err := os.WriteFile("stats-base.md", []byte("# Base\nOne two three four"), 0644)
+		if err != nil {
+			t.Fatalf("failed to write base file: %v", err)
+		}
+		err = os.WriteFile("stats.md", []byte("---\nextends: stats-base.md\n---\n# Leaf"), 0644)
+		if err != nil {
+			t.Fatalf("failed to write stats file: %v", err)
+		}
+
+		cmd := exec.Command(binaryPath, "stats", "stats.md")
+		output, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatalf("stats command failed: %v\nOutput: %s", err, string(output))
+		}
+
+		outputStr := string(output)
+		for _, expected := range []string{"FILE", "TOKENS", "stats-base.md", "stats.md", "TOTAL (output)", "chars/4"} {
+			if !strings.Contains(outputStr, expected) {
+				t.Errorf("expected %q in stats output, got:\n%s", expected, outputStr)
+			}
+		}
+
+		cmd = exec.Command(binaryPath, "stats", "stats.md", "--format", "json")
+		output, err = cmd.Output()
+		if err != nil {
+			t.Fatalf("stats --format json failed: %v", err)
+		}
+
+		var report struct {
+			Files []struct {
+				Path   string `json:"path"`
+				Words  int    `json:"words"`
+				Tokens int    `json:"tokens"`
+			} `json:"files"`
+			Total struct {
+				Bytes int `json:"bytes"`
+			} `json:"total"`
+		}
+		if err := json.Unmarshal(output, &report); err != nil {
+			t.Fatalf("failed to parse stats JSON: %v\nOutput: %s", err, string(output))
+		}
+
+		if len(report.Files) != 2 || report.Files[0].Path != "stats-base.md" || report.Files[0].Words != 6 {
+			t.Errorf("unexpected per-file stats: %+v", report.Files)
+		}
+		if report.Total.Bytes != len("# Base\nOne two three four\n\n# Leaf") {
+			t.Errorf("expected total bytes of built output, got %d", report.Total.Bytes)
+		}
+
+		files := map[string]string{
+			"stats-extra.md": "# Extra",
+			"stats-vars.md":  "---\nvars:\n  team: core\nincludes:\n  - path: stats-extra.md\n    when: profile == \"ci\"\n---\n# {{ .svc }}",
+		}
+		for name, content := range files {
+			if err := os.WriteFile(name, []byte(content), 0644); err != nil {
+				t.Fatalf("failed to write %s: %v", name, err)
+			}
+		}
+
+		if err := exec.Command(binaryPath, "stats", "stats-vars.md").Run(); err == nil {
+			t.Error("expected stats to fail for an undefined variable")
+		}
+
+		cmd = exec.Command(binaryPath, "stats", "stats-vars.md", "--set", "svc=billing", "--profile", "ci", "--format", "json")
+		output, err = cmd.CombinedOutput()
+		if err != nil {
+			t.Fatalf("stats with --set and --profile failed: %v\n%s", err, output)
+		}
+		report.Files = nil
+		if err := json.Unmarshal(output, &report); err != nil {
+			t.Fatalf("failed to parse stats JSON: %v\nOutput: %s", err, string(output))
+		}
+		if len(report.Files) != 2 || report.Total.Bytes != len("# Extra\n\n# billing") {
+			t.Errorf("expected the conditional include and rendered variable to be measured, got %+v", report)
+		}
+	})
+
 	t.Run("init command", func(t *testing.T) {
 		testDir := "init-test"
 		cmd := exec.Command(binaryPath, "init", testDir)
@@ -123,6 +836,47 @@ Main content`
 		}
 	})
 
+	t.Run("validate reports all problems", func(t *testing.T) {
+		err := os.WriteFile("broken.md", []byte("---\nextends: missing-base.md\nincludes:\n  - missing-include.md\nextend: typo.md\n---\n# Broken"), 0644)
+		if err != nil {
+			t.Fatalf("failed to write test file: %v", err)
+		}
+
+		cmd := exec.Command(binaryPath, "validate", "broken.md")
+		output, err := cmd.CombinedOutput()
+		if err == nil {
+			t.Fatalf("expected validation to fail, got: %s", string(output))
+		}
+		for _, expected := range []string{"3 problem(s)", "broken.md:2: file", "broken.md:4: file", `broken.md:5:1: unknown key "extend"`} {
+			if !strings.Contains(string(output), expected) {
+				t.Errorf("expected %q in output, got: %s", expected, string(output))
+			}
+		}
+
+		cmd = exec.Command(binaryPath, "validate", "broken.md", "--format", "json")
+		output, err = cmd.Output()
+		if err == nil {
+			t.Fatal("expected validation to fail")
+		}
+
+		var report struct {
+			Valid    bool `json:"valid"`
+			Problems []struct {
+				Kind string `json:"kind"`
+				Line int    `json:"line"`
+			} `json:"problems"`
+		}
+		if err := json.Unmarshal(output, &report); err != nil {
+			t.Fatalf("invalid JSON output: %v\n%s", err, output)
+		}
+		if report.Valid || len(report.Problems) != 3 {
+			t.Fatalf("unexpected report: %+v", report)
+		}
+		if report.Problems[0].Kind != "frontmatter" || report.Problems[1].Kind != "missing_file" || report.Problems[1].Line != 2 {
+			t.Errorf("unexpected problems: %+v", report.Problems)
+		}
+	})
+
 	t.Run("clean command", func(t *testing.T) {
 		// Test clean for single file
 		content := "# Test Clean\nTest content"
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/hbelmiro/fusectx/pkg/fusectx"
+	"github.com/spf13/cobra"
+)
+
+type contentStats struct {
+	Path   string `json:"path,omitempty"`
+	Bytes  int    `json:"bytes"`
+	Lines  int    `json:"lines"`
+	Words  int    `json:"words"`
+	Tokens int    `json:"tokens"`
+}
+
+type statsReport struct {
+	Source    string         `json:"source"`
+	Tokenizer string         `json:"tokenizer"`
+	Files     []contentStats `json:"files"`
+	Total     contentStats   `json:"total"`
+}
+
+var statsCmd = &cobra.Command{
+	Use:   "stats <source_file>",
+	Short: "Reports size and estimated token count of each file in the dependency chain",
+	Long: `Reports bytes, lines, words and an estimated token count for each file in the dependency chain.
+The total is measured on the built output, so it accounts for de-duplication and block overrides.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		sourceFile := args[0]
+		format, _ := cmd.Flags().GetString("format")
+		tokenizerName, _ := cmd.Flags().GetString("tokenizer")
+		bpeFile, _ := cmd.Flags().GetString("bpe-file")
+		set, _ := cmd.Flags().GetStringArray("set")
+
+		if format != "table" && format != "json" {
+			return fmt.Errorf("unknown format %q (expected table or json)", format)
+		}
+
+		tok, err := fusectx.NewTokenizer(tokenizerName, bpeFile)
+		if err != nil {
+			return err
+		}
+
+		vars, err := parseVars(set)
+		if err != nil {
+			return err
+		}
+
+		r := fusectx.New(append(conditionOptions(cmd), fusectx.WithVars(vars))...)
+		report, err := collectStats(r, sourceFile, tok)
+		if err != nil {
+			return err
+		}
+
+		if format == "json" {
+			encoder := json.NewEncoder(os.Stdout)
+			encoder.SetIndent("", "  ")
+			return encoder.Encode(report)
+		}
+
+		writer := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(writer, "FILE\tBYTES\tLINES\tWORDS\tTOKENS\t")
+		for _, file := range report.Files {
+			fmt.Fprintf(writer, "%s\t%d\t%d\t%d\t%d\t\n", file.Path, file.Bytes, file.Lines, file.Words, file.Tokens)
+		}
+		fmt.Fprintf(writer, "%s\t%d\t%d\t%d\t%d\t\n", "TOTAL (output)", report.Total.Bytes, report.Total.Lines, report.Total.Words, report.Total.Tokens)
+		if err := writer.Flush(); err != nil {
+			return err
+		}
+		fmt.Printf("\nTokens estimated with %s\n", report.Tokenizer)
+
+		return nil
+	},
+}
+
+func collectStats(r *fusectx.Resolver, sourceFile string, tok fusectx.Tokenizer) (*statsReport, error) {
+	chain, err := r.Chain(sourceFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get dependency chain: %w", err)
+	}
+
+	result, err := r.Resolve(sourceFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve %s: %w", sourceFile, err)
+	}
+
+	report := &statsReport{
+		Source:    displayPath(sourceFile),
+		Tokenizer: tok.Name(),
+		Total:     measure(result.Content, tok),
+	}
+
+	for _, dependency := range chain {
+		stats := measure(dependency.Content, tok)
+		stats.Path = displayPath(dependency.Path)
+		report.Files = append(report.Files, stats)
+	}
+
+	return report, nil
+}
+
+func measure(content string, tok fusectx.Tokenizer) contentStats {
+	stats := contentStats{
+		Bytes:  len(content),
+		Words:  len(strings.Fields(content)),
+		Tokens: tok.CountTokens(content),
+	}
+	if content != "" {
+		stats.Lines = strings.Count(strings.TrimSuffix(content, "\n"), "\n") + 1
+	}
+	return stats
+}
+
+func displayPath(path string) string {
+	wd, err := os.Getwd()
+	if err != nil {
+		return path
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return path
+	}
+	rel, err := filepath.Rel(wd, abs)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return abs
+	}
+	return rel
+}
+
+func init() {
+	statsCmd.Flags().StringP("format", "f", "table", "Output format (table or json)")
+	statsCmd.Flags().StringP("tokenizer", "t", "chars", "Tokenizer used to estimate tokens (chars or bpe)")
+	statsCmd.Flags().String("bpe-file", "", "Path to a tiktoken rank file for the bpe tokenizer (default: embedded cl100k_base)")
+	statsCmd.Flags().StringArray("set", nil, "Set a template variable (key=value), overriding frontmatter vars")
+	addConditionFlags(statsCmd)
+
+	rootCmd.AddCommand(statsCmd)
+}
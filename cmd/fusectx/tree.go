@@ -0,0 +1,135 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"slices"
+
+	"github.com/hbelmiro/fusectx/pkg/fusectx"
+	"github.com/spf13/cobra"
+)
+
+var treeCmd = &cobra.Command{
+	Use:   "tree <source_file>",
+	Short: "Prints the dependency tree with line and token counts",
+	Long: `Prints the dependency tree of a source file like tree(1), with the kind of each edge and the line and token counts of each file's own content.
+Files reached again are marked as duplicate and files that close a cycle as cycle, without expanding them.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		sourceFile := args[0]
+		depth, _ := cmd.Flags().GetInt("depth")
+		tokenizerName, _ := cmd.Flags().GetString("tokenizer")
+		bpeFile, _ := cmd.Flags().GetString("bpe-file")
+
+		if depth < 0 {
+			return fmt.Errorf("--depth must not be negative")
+		}
+
+		tok, err := fusectx.NewTokenizer(tokenizerName, bpeFile)
+		if err != nil {
+			return err
+		}
+
+		r := fusectx.New(conditionOptions(cmd)...)
+		graph, err := r.Graph(sourceFile)
+		if err != nil {
+			return fmt.Errorf("failed to build dependency graph: %w", err)
+		}
+
+		p := &treePrinter{
+			resolver: r,
+			tok:      tok,
+			children: make(map[string][]fusectx.GraphEdge),
+			seen:     make(map[string]bool),
+			maxDepth: depth,
+			out:      os.Stdout,
+		}
+		for _, edge := range graph.Edges {
+			p.children[edge.From] = append(p.children[edge.From], edge)
+		}
+
+		root := graph.Nodes[0].Path
+		label, err := p.label(root, "")
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(p.out, label)
+		p.seen[root] = true
+		return p.print(root, "", 1, []string{root})
+	},
+}
+
+type treePrinter struct {
+	resolver *fusectx.Resolver
+	tok      fusectx.Tokenizer
+	children map[string][]fusectx.GraphEdge
+	seen     map[string]bool
+	maxDepth int
+	out      io.Writer
+}
+
+func (p *treePrinter) print(path, prefix string, depth int, ancestors []string) error {
+	if p.maxDepth > 0 && depth > p.maxDepth {
+		return nil
+	}
+
+	children := p.children[path]
+	for i, edge := range children {
+		branch, indent := "├── ", "│   "
+		if i == len(children)-1 {
+			branch, indent = "└── ", "    "
+		}
+
+		label, err := p.label(edge.To, edge.Selector)
+		if err != nil {
+			return err
+		}
+		line := prefix + branch + edge.Relation + " " + label
+
+		entry := edge.To + edge.Selector
+		cycle := slices.Contains(ancestors, edge.To)
+		duplicate := !cycle && p.seen[entry]
+		switch {
+		case cycle:
+			line += " [cycle]"
+		case duplicate:
+			line += " [duplicate]"
+		}
+		fmt.Fprintln(p.out, line)
+		p.seen[entry] = true
+
+		if cycle || duplicate {
+			continue
+		}
+		if err := p.print(edge.To, prefix+indent, depth+1, append(ancestors, edge.To)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *treePrinter) label(path, selector string) (string, error) {
+	content, err := p.resolver.Content(path + selector)
+	if err != nil {
+		return "", err
+	}
+	stats := measure(content, p.tok)
+	return fmt.Sprintf("%s%s (%s, %s)", displayPath(path), selector, plural(stats.Lines, "line"), plural(stats.Tokens, "token")), nil
+}
+
+func plural(n int, noun string) string {
+	if n == 1 {
+		return "1 " + noun
+	}
+	return fmt.Sprintf("%d %ss", n, noun)
+}
+
+func init() {
+	treeCmd.Flags().IntP("depth", "d", 0, "Maximum depth to print (0 for no limit)")
+	treeCmd.Flags().StringP("tokenizer", "t", "chars", "Tokenizer used to estimate tokens (chars or bpe)")
+	treeCmd.Flags().String("bpe-file", "", "Path to a tiktoken rank file for the bpe tokenizer (default: embedded cl100k_base)")
+	addConditionFlags(treeCmd)
+
+	rootCmd.AddCommand(treeCmd)
+}
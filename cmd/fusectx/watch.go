@@ -0,0 +1,164 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/hbelmiro/fusectx/pkg/fusectx"
+)
+
+const watchDebounce = 100 * time.Millisecond
+
+type watcher struct {
+	resolver *fusectx.Resolver
+	notify   *fsnotify.Watcher
+	rebuild  func(target string)
+	silent   bool
+	targets  map[string][]string
+	dirs     map[string]bool
+}
+
+func watch(r *fusectx.Resolver, targets []string, rebuild func(target string), silent bool) error {
+	notify, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("failed to start watching: %w", err)
+	}
+	defer notify.Close()
+
+	w := &watcher{
+		resolver: r,
+		notify:   notify,
+		rebuild:  rebuild,
+		silent:   silent,
+		targets:  make(map[string][]string),
+		dirs:     make(map[string]bool),
+	}
+	for _, target := range targets {
+		w.refresh(target)
+	}
+	w.sync()
+
+	if !silent {
+		fmt.Fprintf(os.Stderr, "Watching %d files for changes...\n", len(w.files()))
+	}
+
+	changed := make(map[string]bool)
+	created := false
+	var flush <-chan time.Time
+
+	for {
+		select {
+		case event, ok := <-notify.Events:
+			if !ok {
+				return nil
+			}
+			if event.Has(fsnotify.Chmod) && !event.Has(fsnotify.Write) {
+				continue
+			}
+			changed[filepath.Clean(event.Name)] = true
+			created = created || event.Has(fsnotify.Create) || event.Has(fsnotify.Rename)
+			flush = time.After(watchDebounce)
+		case err, ok := <-notify.Errors:
+			if !ok {
+				return nil
+			}
+			fmt.Fprintf(os.Stderr, "Watch error: %v\n", err)
+		case <-flush:
+			w.update(changed, created)
+			changed = make(map[string]bool)
+			created = false
+			flush = nil
+		}
+	}
+}
+
+func (w *watcher) update(changed map[string]bool, created bool) {
+	var affected []string
+	for _, target := range slices.Sorted(maps.Keys(w.targets)) {
+		files := w.targets[target]
+		if slices.ContainsFunc(files, func(file string) bool { return changed[file] }) {
+			affected = append(affected, target)
+			continue
+		}
+		if created && w.refresh(target) {
+			affected = append(affected, target)
+		}
+	}
+
+	for _, target := range affected {
+		if !w.silent {
+			fmt.Fprintf(os.Stderr, "Rebuilding %s...\n", target)
+		}
+		w.rebuild(target)
+		w.refresh(target)
+	}
+	w.sync()
+}
+
+func (w *watcher) refresh(target string) bool {
+	files := []string{absPath(target)}
+
+	chain, err := w.resolver.Chain(target)
+	if err != nil {
+		files = append(files, w.targets[target]...)
+	}
+	for _, dependency := range chain {
+		files = append(files, absPath(dependency.File))
+	}
+	slices.Sort(files)
+	files = slices.Compact(files)
+
+	previous, ok := w.targets[target]
+	w.targets[target] = files
+	return !ok || !slices.Equal(previous, files)
+}
+
+func (w *watcher) files() []string {
+	var files []string
+	for _, dependencies := range w.targets {
+		files = append(files, dependencies...)
+	}
+	slices.Sort(files)
+	return slices.Compact(files)
+}
+
+func (w *watcher) sync() {
+	needed := make(map[string]bool)
+	for _, file := range w.files() {
+		needed[filepath.Dir(file)] = true
+	}
+
+	for dir := range w.dirs {
+		if !needed[dir] {
+			w.notify.Remove(dir)
+			delete(w.dirs, dir)
+		}
+	}
+	for dir := range needed {
+		if w.dirs[dir] {
+			continue
+		}
+		if err := w.notify.Add(dir); err != nil {
+			if !errors.Is(err, fs.ErrNotExist) {
+				fmt.Fprintf(os.Stderr, "Failed to watch %s: %v\n", dir, err)
+			}
+			continue
+		}
+		w.dirs[dir] = true
+	}
+}
+
+func absPath(path string) string {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return filepath.Clean(path)
+	}
+	return abs
+}
@@ -0,0 +1,211 @@
+// Package diff produces unified diffs of text files.
+package diff
+
+import (
+	"fmt"
+	"strings"
+)
+
+const context = 3
+
+type edit struct {
+	op   byte
+	line string
+}
+
+func Unified(fromName, toName, from, to string) string {
+	if from == to {
+		return ""
+	}
+	return format(fromName, toName, diffLines(splitLines(from), splitLines(to)))
+}
+
+func Added(fromName, toName, to string) string {
+	if to == "" {
+		return ""
+	}
+	var edits []edit
+	for _, line := range splitLines(to) {
+		edits = append(edits, edit{op: '+', line: line})
+	}
+	return format(fromName, toName, edits)
+}
+
+func format(fromName, toName string, edits []edit) string {
+	var result strings.Builder
+	fmt.Fprintf(&result, "--- %s\n+++ %s\n", fromName, toName)
+
+	fromLines, toLines := make([]int, len(edits)+1), make([]int, len(edits)+1)
+	for i, e := range edits {
+		fromLines[i+1], toLines[i+1] = fromLines[i], toLines[i]
+		if e.op != '+' {
+			fromLines[i+1]++
+		}
+		if e.op != '-' {
+			toLines[i+1]++
+		}
+	}
+
+	for i := 0; i < len(edits); {
+		if edits[i].op == ' ' {
+			i++
+			continue
+		}
+
+		start, end := max(0, i-context), i
+		for end < len(edits) {
+			if edits[end].op != ' ' {
+				end++
+				continue
+			}
+			run := 0
+			for end+run < len(edits) && edits[end+run].op == ' ' {
+				run++
+			}
+			if end+run == len(edits) || run > 2*context {
+				break
+			}
+			end += run
+		}
+		end = min(len(edits), end+context)
+
+		fmt.Fprintf(&result, "@@ -%s +%s @@\n",
+			hunkRange(fromLines[start], fromLines[end]-fromLines[start]),
+			hunkRange(toLines[start], toLines[end]-toLines[start]))
+		for _, e := range edits[start:end] {
+			result.WriteByte(e.op)
+			result.WriteString(e.line)
+			if !strings.HasSuffix(e.line, "\n") {
+				result.WriteString("\n\\ No newline at end of file\n")
+			}
+		}
+		i = end
+	}
+
+	return result.String()
+}
+
+func hunkRange(start, count int) string {
+	switch count {
+	case 0:
+		return fmt.Sprintf("%d,0", start)
+	case 1:
+		return fmt.Sprintf("%d", start+1)
+	default:
+		return fmt.Sprintf("%d,%d", start+1, count)
+	}
+}
+
+func splitLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+	lines := strings.SplitAfter(text, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+type differ struct {
+	from, to []string
+	edits    []edit
+}
+
+func diffLines(from, to []string) []edit {
+	d := &differ{from: from, to: to}
+	d.compare(0, len(from), 0, len(to))
+	return d.edits
+}
+
+func (d *differ) compare(fromLo, fromHi, toLo, toHi int) {
+	for fromLo < fromHi && toLo < toHi && d.from[fromLo] == d.to[toLo] {
+		d.edits = append(d.edits, edit{op: ' ', line: d.from[fromLo]})
+		fromLo++
+		toLo++
+	}
+	suffix := 0
+	for fromLo < fromHi-suffix && toLo < toHi-suffix && d.from[fromHi-suffix-1] == d.to[toHi-suffix-1] {
+		suffix++
+	}
+	fromHi -= suffix
+	toHi -= suffix
+
+	switch {
+	case fromLo == fromHi:
+		for _, line := range d.to[toLo:toHi] {
+			d.edits = append(d.edits, edit{op: '+', line: line})
+		}
+	case toLo == toHi:
+		for _, line := range d.from[fromLo:fromHi] {
+			d.edits = append(d.edits, edit{op: '-', line: line})
+		}
+	default:
+		x, y, u, v := d.middleSnake(fromLo, fromHi, toLo, toHi)
+		d.compare(fromLo, x, toLo, y)
+		for _, line := range d.from[x:u] {
+			d.edits = append(d.edits, edit{op: ' ', line: line})
+		}
+		d.compare(u, fromHi, v, toHi)
+	}
+
+	for _, line := range d.from[fromHi : fromHi+suffix] {
+		d.edits = append(d.edits, edit{op: ' ', line: line})
+	}
+}
+
+func (d *differ) middleSnake(fromLo, fromHi, toLo, toHi int) (int, int, int, int) {
+	n, m := fromHi-fromLo, toHi-toLo
+	delta := n - m
+	odd := delta%2 != 0
+	limit := (n + m + 1) / 2
+	offset := limit + 1
+	forward := make([]int, 2*offset+1)
+	backward := make([]int, 2*offset+1)
+
+	for depth := 0; depth <= limit; depth++ {
+		for k := -depth; k <= depth; k += 2 {
+			x := next(forward, offset, k, depth)
+			y := x - k
+			startX, startY := x, y
+			for x < n && y < m && d.from[fromLo+x] == d.to[toLo+y] {
+				x++
+				y++
+			}
+			forward[offset+k] = x
+			if odd && abs(delta-k) <= depth-1 && x+backward[offset+delta-k] >= n {
+				return fromLo + startX, toLo + startY, fromLo + x, toLo + y
+			}
+		}
+
+		for k := -depth; k <= depth; k += 2 {
+			x := next(backward, offset, k, depth)
+			y := x - k
+			startX, startY := x, y
+			for x < n && y < m && d.from[fromHi-x-1] == d.to[toHi-y-1] {
+				x++
+				y++
+			}
+			backward[offset+k] = x
+			if !odd && abs(delta-k) <= depth && x+forward[offset+delta-k] >= n {
+				return fromHi - x, toHi - y, fromHi - startX, toHi - startY
+			}
+		}
+	}
+
+	panic("diff: no middle snake found")
+}
+
+func next(v []int, offset, k, depth int) int {
+	if k == -depth || (k != depth && v[offset+k-1] < v[offset+k+1]) {
+		return v[offset+k+1]
+	}
+	return v[offset+k-1] + 1
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
@@ -0,0 +1,109 @@
+package diff
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestUnified(t *testing.T) {
+	tests := []struct {
+		name     string
+		from     string
+		to       string
+		expected string
+	}{
+		{
+			name: "equal",
+			from: "a\nb\n",
+			to:   "a\nb\n",
+		},
+		{
+			name: "separate hunks",
+			from: "a\nb\nc\nd\ne\nf\ng\nh\ni\nj\nk\nl\nm\n",
+			to:   "a\nB\nc\nd\ne\nf\ng\nh\ni\nj\nk\nl\nm\nn",
+			expected: "--- old\n+++ new\n" +
+				"@@ -1,5 +1,5 @@\n a\n-b\n+B\n c\n d\n e\n" +
+				"@@ -11,3 +11,4 @@\n k\n l\n m\n+n\n\\ No newline at end of file\n",
+		},
+		{
+			name:     "merged hunk",
+			from:     "a\nb\nc\nd\ne\nf\ng\n",
+			to:       "A\nb\nc\nd\ne\nf\nG\n",
+			expected: "--- old\n+++ new\n@@ -1,7 +1,7 @@\n-a\n+A\n b\n c\n d\n e\n f\n-g\n+G\n",
+		},
+		{
+			name:     "missing newline",
+			from:     "x\ny",
+			to:       "x\ny\n",
+			expected: "--- old\n+++ new\n@@ -1,2 +1,2 @@\n x\n-y\n\\ No newline at end of file\n+y\n",
+		},
+		{
+			name:     "from empty",
+			from:     "",
+			to:       "one\n",
+			expected: "--- old\n+++ new\n@@ -0,0 +1 @@\n+one\n",
+		},
+		{
+			name:     "to empty",
+			from:     "one\ntwo\n",
+			to:       "",
+			expected: "--- old\n+++ new\n@@ -1,2 +0,0 @@\n-one\n-two\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			actual := Unified("old", "new", tt.from, tt.to)
+			if actual != tt.expected {
+				t.Errorf("expected:\n%s\ngot:\n%s", tt.expected, actual)
+			}
+		})
+	}
+}
+
+func TestUnifiedLargeRewrite(t *testing.T) {
+	var from, to strings.Builder
+	for i := range 5000 {
+		fmt.Fprintf(&from, "line %d\n", i)
+		if i%2 == 0 {
+			fmt.Fprintf(&to, "line %d\n", i)
+		} else {
+			fmt.Fprintf(&to, "changed %d\n", i)
+		}
+	}
+
+	actual := Unified("old", "new", from.String(), to.String())
+	if removed, added := strings.Count(actual, "\n-line"), strings.Count(actual, "\n+changed"); removed != 2500 || added != 2500 {
+		t.Errorf("expected 2500 removed and added lines, got %d and %d", removed, added)
+	}
+}
+
+func TestAdded(t *testing.T) {
+	tests := []struct {
+		name     string
+		to       string
+		expected string
+	}{
+		{
+			name: "empty",
+		},
+		{
+			name:     "lines",
+			to:       "one\ntwo",
+			expected: "--- old\n+++ new\n@@ -0,0 +1,2 @@\n+one\n+two\n\\ No newline at end of file\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			actual := Added("old", "new", tt.to)
+			if actual != tt.expected {
+				t.Errorf("expected:\n%s\ngot:\n%s", tt.expected, actual)
+			}
+			if actual != Unified("old", "new", "", tt.to) {
+				t.Errorf("expected the same output as Unified from an empty file")
+			}
+		})
+	}
+}
@@ -0,0 +1,178 @@
+package resolver
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+const (
+	blockReplace = "replace"
+	blockAppend  = "append"
+	blockPrepend = "prepend"
+	blockDelete  = "delete"
+)
+
+var blockMarkerPattern = regexp.MustCompile(`<!--\s*(?:block:([A-Za-z0-9_.-]+)(?:\s+(replace|append|prepend|delete))?|(endblock))\s*-->`)
+
+type blockNode struct {
+	text         string
+	name         string
+	mode         string
+	children     []*blockNode
+	startOwnLine bool
+	endOwnLine   bool
+	deleted      bool
+}
+
+func parseBlocks(content string) ([]*blockNode, error) {
+	root := &blockNode{}
+	stack := []*blockNode{root}
+	pos := 0
+
+	for _, m := range blockMarkerPattern.FindAllStringSubmatchIndex(content, -1) {
+		start, end := m[0], m[1]
+		current := stack[len(stack)-1]
+
+		if start > pos {
+			current.children = append(current.children, &blockNode{text: content[pos:start]})
+		}
+
+		ownLine := (start == 0 || content[start-1] == '\n') && (end == len(content) || content[end] == '\n')
+		if ownLine && end < len(content) {
+			end++
+		}
+		pos = end
+
+		if m[6] >= 0 {
+			if len(stack) == 1 {
+				return nil, fmt.Errorf("endblock without a matching block")
+			}
+			current.endOwnLine = ownLine
+			stack = stack[:len(stack)-1]
+			continue
+		}
+
+		block := &blockNode{name: content[m[2]:m[3]], startOwnLine: ownLine}
+		if m[4] >= 0 {
+			block.mode = content[m[4]:m[5]]
+		}
+		current.children = append(current.children, block)
+
+		if block.mode != blockDelete {
+			stack = append(stack, block)
+		}
+	}
+
+	if len(stack) > 1 {
+		return nil, fmt.Errorf("block %q is never closed", stack[len(stack)-1].name)
+	}
+
+	if pos < len(content) {
+		root.children = append(root.children, &blockNode{text: content[pos:]})
+	}
+
+	return root.children, nil
+}
+
+func renderBlocks(nodes []*blockNode, markers bool) string {
+	var result strings.Builder
+	writeBlocks(&result, nodes, markers)
+	return result.String()
+}
+
+func writeBlocks(result *strings.Builder, nodes []*blockNode, markers bool) {
+	for _, node := range nodes {
+		if node.deleted {
+			continue
+		}
+		if node.name == "" {
+			result.WriteString(node.text)
+			continue
+		}
+
+		if markers {
+			result.WriteString("<!-- block:" + node.name + " -->")
+			if node.startOwnLine {
+				result.WriteString("\n")
+			}
+		}
+
+		writeBlocks(result, node.children, markers)
+
+		if markers {
+			result.WriteString("<!-- endblock -->")
+			if node.endOwnLine {
+				result.WriteString("\n")
+			}
+		}
+	}
+}
+
+func stripBlocks(content string) (string, error) {
+	nodes, err := parseBlocks(content)
+	if err != nil {
+		return "", err
+	}
+	return renderBlocks(nodes, false), nil
+}
+
+func indexBlocks(nodes []*blockNode, index map[string][]*blockNode) map[string][]*blockNode {
+	if index == nil {
+		index = make(map[string][]*blockNode)
+	}
+	for _, node := range nodes {
+		if node.name == "" {
+			continue
+		}
+		index[node.name] = append(index[node.name], node)
+		indexBlocks(node.children, index)
+	}
+	return index
+}
+
+func overrideBlocks(parent map[string][]*blockNode, own []*blockNode, strict bool) ([]*blockNode, error) {
+	var remaining []*blockNode
+
+	for _, node := range own {
+		if node.name == "" {
+			remaining = append(remaining, node)
+			continue
+		}
+
+		targets := parent[node.name]
+		if len(targets) == 0 {
+			if strict && node.mode != "" && node.mode != blockReplace {
+				return nil, fmt.Errorf("cannot %s block %q: it is not defined by the parent", node.mode, node.name)
+			}
+			if node.mode == blockDelete {
+				continue
+			}
+
+			children, err := overrideBlocks(parent, node.children, strict)
+			if err != nil {
+				return nil, err
+			}
+			node.children = children
+			node.mode = ""
+			remaining = append(remaining, node)
+			continue
+		}
+
+		for _, target := range targets {
+			switch node.mode {
+			case blockAppend:
+				target.children = slices.Concat(target.children, node.children)
+			case blockPrepend:
+				target.children = slices.Concat(node.children, target.children)
+			case blockDelete:
+				target.deleted = true
+			default:
+				target.children = node.children
+			}
+		}
+	}
+
+	return remaining, nil
+}
@@ -0,0 +1,147 @@
+package resolver
+
+import (
+	"testing"
+)
+
+func TestParseBlocks(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		stripped string
+		marked   string
+		hasError bool
+	}{
+		{
+			name:     "no blocks",
+			content:  "# Title\nText",
+			stripped: "# Title\nText",
+			marked:   "# Title\nText",
+		},
+		{
+			name:     "blocks on their own lines",
+			content:  "Intro\n<!-- block:style -->\nUse tabs.\n<!-- endblock -->\nOutro",
+			stripped: "Intro\nUse tabs.\nOutro",
+			marked:   "Intro\n<!-- block:style -->\nUse tabs.\n<!-- endblock -->\nOutro",
+		},
+		{
+			name:     "inline block",
+			content:  "Written in <!--block:lang-->Go<!--endblock-->.",
+			stripped: "Written in Go.",
+			marked:   "Written in <!-- block:lang -->Go<!-- endblock -->.",
+		},
+		{
+			name:     "nested blocks",
+			content:  "<!-- block:outer -->\nA\n<!-- block:inner -->\nB\n<!-- endblock -->\n<!-- endblock -->",
+			stripped: "A\nB\n",
+			marked:   "<!-- block:outer -->\nA\n<!-- block:inner -->\nB\n<!-- endblock -->\n<!-- endblock -->\n",
+		},
+		{
+			name:     "unclosed block",
+			content:  "<!-- block:style -->\nUse tabs.",
+			hasError: true,
+		},
+		{
+			name:     "endblock without block",
+			content:  "Text\n<!-- endblock -->",
+			hasError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			nodes, err := parseBlocks(tt.content)
+
+			if tt.hasError && err == nil {
+				t.Error("expected error but got none")
+			}
+			if !tt.hasError && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if tt.hasError {
+				return
+			}
+
+			if stripped := renderBlocks(nodes, false); stripped != tt.stripped {
+				t.Errorf("expected stripped %q, got %q", tt.stripped, stripped)
+			}
+			if marked := renderBlocks(nodes, true); marked != tt.marked {
+				t.Errorf("expected marked %q, got %q", tt.marked, marked)
+			}
+		})
+	}
+}
+
+func TestOverrideBlocks(t *testing.T) {
+	parent := "# Base\n<!-- block:style -->\nUse tabs.\n<!-- endblock -->\n<!-- block:extra -->\nExtra.\n<!-- endblock -->\nEnd"
+
+	tests := []struct {
+		name     string
+		own      string
+		parent   string
+		rest     string
+		hasError bool
+	}{
+		{
+			name:   "replace",
+			own:    "<!-- block:style -->\nUse spaces.\n<!-- endblock -->\n# Child",
+			parent: "# Base\nUse spaces.\nExtra.\nEnd",
+			rest:   "# Child",
+		},
+		{
+			name:   "append and prepend",
+			own:    "<!-- block:style append -->\nWrap at 100.\n<!-- endblock -->\n<!-- block:extra prepend -->\nFirst.\n<!-- endblock -->",
+			parent: "# Base\nUse tabs.\nWrap at 100.\nFirst.\nExtra.\nEnd",
+			rest:   "",
+		},
+		{
+			name:   "delete",
+			own:    "<!-- block:extra delete -->\n# Child",
+			parent: "# Base\nUse tabs.\nEnd",
+			rest:   "# Child",
+		},
+		{
+			name:   "new blocks are kept in place",
+			own:    "<!-- block:child -->\nChild block.\n<!-- endblock -->",
+			parent: "# Base\nUse tabs.\nExtra.\nEnd",
+			rest:   "Child block.\n",
+		},
+		{
+			name:     "append to undefined block",
+			own:      "<!-- block:missing append -->\nText\n<!-- endblock -->",
+			hasError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parentBlocks, err := parseBlocks(parent)
+			if err != nil {
+				t.Fatalf("failed to parse parent: %v", err)
+			}
+			ownBlocks, err := parseBlocks(tt.own)
+			if err != nil {
+				t.Fatalf("failed to parse own content: %v", err)
+			}
+
+			rest, err := overrideBlocks(indexBlocks(parentBlocks, nil), ownBlocks, true)
+
+			if tt.hasError && err == nil {
+				t.Error("expected error but got none")
+			}
+			if !tt.hasError && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if tt.hasError {
+				return
+			}
+
+			if actual := renderBlocks(parentBlocks, false); actual != tt.parent {
+				t.Errorf("expected parent %q, got %q", tt.parent, actual)
+			}
+			if actual := renderBlocks(rest, false); actual != tt.rest {
+				t.Errorf("expected rest %q, got %q", tt.rest, actual)
+			}
+		})
+	}
+}
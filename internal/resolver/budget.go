@@ -0,0 +1,105 @@
+package resolver
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/hbelmiro/fusectx/internal/tokenizer"
+)
+
+type Cut struct {
+	Path            string
+	Priority        int
+	Dropped         bool
+	SectionsRemoved int
+	Sections        int
+}
+
+func (c Cut) String() string {
+	if c.Dropped {
+		return fmt.Sprintf("dropped %s (priority %d)", c.Path, c.Priority)
+	}
+	return fmt.Sprintf("truncated %s (priority %d): removed %d of %d sections", c.Path, c.Priority, c.SectionsRemoved, c.Sections)
+}
+
+func fitBudget(segments []segment, output func([]segment) (string, error), maxTokens int, tok tokenizer.Tokenizer, strict bool) ([]segment, []Cut, error) {
+	segments = append([]segment(nil), segments...)
+	var cuts []Cut
+	cutIndex := make(map[int]int)
+
+	for {
+		content, err := output(segments)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		tokens := tok.CountTokens(content)
+		if tokens <= maxTokens {
+			return segments, cuts, nil
+		}
+		if strict {
+			return nil, nil, fmt.Errorf("output has %d tokens, exceeding the budget of %d", tokens, maxTokens)
+		}
+
+		i := lowestPriority(segments)
+		if i < 0 {
+			return nil, nil, fmt.Errorf("output has %d tokens after dropping all includes, exceeding the budget of %d", tokens, maxTokens)
+		}
+
+		stripped, err := stripBlocks(segments[i].content)
+		if err != nil {
+			return nil, nil, fmt.Errorf("error parsing blocks in %s: %w", segments[i].path, err)
+		}
+		sections := splitSections(strings.TrimSpace(stripped))
+
+		n, ok := cutIndex[i]
+		if !ok {
+			cuts = append(cuts, Cut{Path: segments[i].path, Priority: priorityOf(segments[i]), Sections: len(sections)})
+			n = len(cuts) - 1
+			cutIndex[i] = n
+		}
+
+		if len(sections) > 1 {
+			segments[i].content = strings.Join(sections[:len(sections)-1], "")
+			cuts[n].SectionsRemoved++
+		} else {
+			segments[i].content = ""
+			cuts[n].Dropped = true
+		}
+	}
+}
+
+func lowestPriority(segments []segment) int {
+	lowest := -1
+	for i, seg := range segments {
+		if !seg.included || strings.TrimSpace(seg.content) == "" {
+			continue
+		}
+		if lowest < 0 || priorityOf(seg) <= priorityOf(segments[lowest]) {
+			lowest = i
+		}
+	}
+	return lowest
+}
+
+func priorityOf(seg segment) int {
+	if seg.priority == nil {
+		return 0
+	}
+	return *seg.priority
+}
+
+func splitSections(content string) []string {
+	lines := strings.SplitAfter(content, "\n")
+	var sections []string
+	start := 0
+
+	for _, h := range findHeadings(lines) {
+		if h.line > start {
+			sections = append(sections, strings.Join(lines[start:h.line], ""))
+			start = h.line
+		}
+	}
+
+	return append(sections, strings.Join(lines[start:], ""))
+}
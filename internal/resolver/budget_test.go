@@ -0,0 +1,113 @@
+package resolver
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSplitSections(t *testing.T) {
+	content := "Intro\n# One\nText one\n## Two\nText two\n```\n# not a heading\n```"
+	sections := splitSections(content)
+
+	expected := []string{"Intro\n", "# One\nText one\n", "## Two\nText two\n```\n# not a heading\n```"}
+	if strings.Join(sections, "|") != strings.Join(expected, "|") {
+		t.Errorf("expected %q, got %q", expected, sections)
+	}
+}
+
+func TestTokenBudget(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "fusectx-budget-test")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	files := map[string]string{
+		"base.md":      "# Base\nBase rules that always stay.",
+		"optional.md":  "# Optional\nNice to have background that can go first.",
+		"important.md": "# Important\nKeep this.\n\n## Details\nLong details that can be truncated away.",
+		"main.md": `---
+extends: base.md
+includes:
+  - path: important.md
+    priority: 10
+  - optional.md
+max_tokens: 1000
+---
+# Main`,
+	}
+
+	for filename, content := range files {
+		err := os.WriteFile(filepath.Join(tmpDir, filename), []byte(content), 0644)
+		if err != nil {
+			t.Fatalf("failed to write test file %s: %v", filename, err)
+		}
+	}
+
+	tests := []struct {
+		name     string
+		opts     Options
+		expected string
+		cuts     []string
+		hasError bool
+	}{
+		{
+			name:     "within frontmatter budget",
+			opts:     Options{},
+			expected: "# Base\nBase rules that always stay.\n\n# Important\nKeep this.\n\n## Details\nLong details that can be truncated away.\n\n# Optional\nNice to have background that can go first.\n\n# Main",
+		},
+		{
+			name:     "lowest priority include is dropped first",
+			opts:     Options{MaxTokens: 40},
+			expected: "# Base\nBase rules that always stay.\n\n# Important\nKeep this.\n\n## Details\nLong details that can be truncated away.\n\n# Main",
+			cuts:     []string{"dropped optional.md (priority 0)"},
+		},
+		{
+			name:     "higher priority include is truncated at section boundaries",
+			opts:     Options{MaxTokens: 20},
+			expected: "# Base\nBase rules that always stay.\n\n# Important\nKeep this.\n\n# Main",
+			cuts:     []string{"dropped optional.md (priority 0)", "truncated important.md (priority 10): removed 1 of 2 sections"},
+		},
+		{
+			name:     "strict mode fails",
+			opts:     Options{MaxTokens: 40, Strict: true},
+			hasError: true,
+		},
+		{
+			name:     "budget too small for pinned content",
+			opts:     Options{MaxTokens: 5},
+			hasError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := ResolveWithOptions(filepath.Join(tmpDir, "main.md"), tt.opts)
+
+			if tt.hasError && err == nil {
+				t.Error("expected error but got none")
+			}
+			if !tt.hasError && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if tt.hasError {
+				return
+			}
+
+			if result.Content != tt.expected {
+				t.Errorf("expected:\n%s\n\ngot:\n%s", tt.expected, result.Content)
+			}
+
+			var cuts []string
+			for _, cut := range result.Cuts {
+				cut.Path = filepath.Base(cut.Path)
+				cuts = append(cuts, cut.String())
+			}
+			if strings.Join(cuts, "\n") != strings.Join(tt.cuts, "\n") {
+				t.Errorf("expected cuts %q, got %q", tt.cuts, cuts)
+			}
+		})
+	}
+}
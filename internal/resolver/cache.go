@@ -0,0 +1,70 @@
+package resolver
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+)
+
+type ParseCache struct {
+	mu    sync.Mutex
+	files map[string]*parsedFile
+}
+
+type parsedFile struct {
+	once        sync.Once
+	frontmatter *Frontmatter
+	content     string
+	openErr     error
+	err         error
+}
+
+func NewParseCache() *ParseCache {
+	return &ParseCache{files: make(map[string]*parsedFile)}
+}
+
+func (c *ParseCache) load(files fileSystem, absPath string) *parsedFile {
+	if c == nil {
+		parsed := &parsedFile{}
+		parsed.parse(files, absPath)
+		return parsed
+	}
+
+	c.mu.Lock()
+	parsed, ok := c.files[absPath]
+	if !ok {
+		parsed = &parsedFile{}
+		c.files[absPath] = parsed
+	}
+	c.mu.Unlock()
+
+	parsed.once.Do(func() {
+		parsed.parse(files, absPath)
+	})
+	return parsed
+}
+
+func (p *parsedFile) parse(files fileSystem, absPath string) {
+	file, err := files.open(absPath)
+	if err != nil {
+		p.openErr = err
+		return
+	}
+	defer file.Close()
+
+	frontmatter, content, err := parseFrontmatter(file, false)
+	if err != nil {
+		var frontmatterErr *FrontmatterError
+		if errors.As(err, &frontmatterErr) {
+			frontmatterErr.Path = absPath
+			p.err = frontmatterErr
+			return
+		}
+		p.err = fmt.Errorf("error parsing file %s: %w", absPath, err)
+		return
+	}
+	for _, unknown := range frontmatter.unknownKeys {
+		unknown.Path = absPath
+	}
+	p.frontmatter, p.content = frontmatter, content
+}
@@ -0,0 +1,83 @@
+package resolver
+
+import (
+	"errors"
+	"io/fs"
+	"sync"
+	"testing"
+	"testing/fstest"
+)
+
+type countingFS struct {
+	fstest.MapFS
+	mu    sync.Mutex
+	opens map[string]int
+}
+
+func (c *countingFS) Open(name string) (fs.File, error) {
+	c.mu.Lock()
+	c.opens[name]++
+	c.mu.Unlock()
+	return c.MapFS.Open(name)
+}
+
+func TestParseCache(t *testing.T) {
+	fsys := &countingFS{
+		MapFS: fstest.MapFS{
+			"base.md":   {Data: []byte("---\nvars:\n  team: core\n---\n# Base {{ .team }}")},
+			"shared.md": {Data: []byte("# Shared")},
+			"a.md":      {Data: []byte("---\nextends: base.md\nincludes:\n  - shared.md\n---\n# A")},
+			"b.md":      {Data: []byte("---\nextends: base.md\nincludes:\n  - shared.md\n---\n# B")},
+		},
+		opens: make(map[string]int),
+	}
+	opts := Options{FS: fsys, Cache: NewParseCache()}
+
+	expected := map[string]string{
+		"a.md": "# Base core\n\n# Shared\n\n# A",
+		"b.md": "# Base core\n\n# Shared\n\n# B",
+	}
+
+	var wg sync.WaitGroup
+	results := make([]string, 8)
+	errs := make([]error, 8)
+	for i := range results {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var result *Result
+			result, errs[i] = ResolveWithOptions([]string{"a.md", "b.md"}[i%2], opts)
+			if result != nil {
+				results[i] = result.Content
+			}
+		}()
+	}
+	wg.Wait()
+
+	for i, content := range results {
+		if errs[i] != nil {
+			t.Fatalf("unexpected error: %v", errs[i])
+		}
+		if want := expected[[]string{"a.md", "b.md"}[i%2]]; content != want {
+			t.Errorf("expected %q, got %q", want, content)
+		}
+	}
+	for name, count := range fsys.opens {
+		if count != 1 {
+			t.Errorf("expected %s to be opened once, got %d", name, count)
+		}
+	}
+
+	fsys.MapFS["c.md"] = &fstest.MapFile{Data: []byte("---\nincludes:\n  - missing.md\n---\n# C")}
+	fsys.MapFS["d.md"] = &fstest.MapFile{Data: []byte("---\nextends: missing.md\n---\n# D")}
+	for _, referrer := range []string{"c.md", "d.md"} {
+		_, err := ResolveWithOptions(referrer, opts)
+		var missing *MissingFileError
+		if !errors.As(err, &missing) {
+			t.Fatalf("expected MissingFileError for %s, got %v", referrer, err)
+		}
+		if missing.Referrer != referrer {
+			t.Errorf("expected referrer %s, got %s", referrer, missing.Referrer)
+		}
+	}
+}
@@ -0,0 +1,268 @@
+package resolver
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+type Condition struct {
+	Profile StringList `yaml:"profile"`
+	Tag     StringList `yaml:"tag"`
+	Env     StringList `yaml:"env"`
+
+	expr conditionNode
+}
+
+func (c *Condition) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind != yaml.ScalarNode {
+		type plain Condition
+		return node.Decode((*plain)(c))
+	}
+
+	expr, err := parseCondition(node.Value)
+	if err != nil {
+		return fmt.Errorf("line %d, column %d: invalid condition %q: %w", node.Line, node.Column, node.Value, err)
+	}
+	c.expr = expr
+	return nil
+}
+
+type activation struct {
+	profile string
+	tags    []string
+}
+
+func (a activation) matches(c *Condition) bool {
+	if c == nil {
+		return true
+	}
+	if c.expr != nil {
+		return c.expr.eval(a)
+	}
+
+	if len(c.Profile) > 0 && !slices.Contains(c.Profile, a.profile) {
+		return false
+	}
+	if len(c.Tag) > 0 && !slices.ContainsFunc(c.Tag, func(tag string) bool {
+		return slices.Contains(a.tags, tag)
+	}) {
+		return false
+	}
+	if len(c.Env) > 0 && !slices.ContainsFunc(c.Env, func(name string) bool {
+		return os.Getenv(name) != ""
+	}) {
+		return false
+	}
+	return true
+}
+
+func (a activation) lookup(name string) []string {
+	switch {
+	case name == "profile":
+		if a.profile == "" {
+			return nil
+		}
+		return []string{a.profile}
+	case name == "tag":
+		return a.tags
+	default:
+		if value := os.Getenv(strings.TrimPrefix(name, "env.")); value != "" {
+			return []string{value}
+		}
+		return nil
+	}
+}
+
+type conditionNode interface {
+	eval(a activation) bool
+}
+
+type notNode struct {
+	operand conditionNode
+}
+
+func (n notNode) eval(a activation) bool {
+	return !n.operand.eval(a)
+}
+
+type logicalNode struct {
+	op          string
+	left, right conditionNode
+}
+
+func (n logicalNode) eval(a activation) bool {
+	if n.op == "&&" {
+		return n.left.eval(a) && n.right.eval(a)
+	}
+	return n.left.eval(a) || n.right.eval(a)
+}
+
+type compareNode struct {
+	name  string
+	op    string
+	value string
+}
+
+func (n compareNode) eval(a activation) bool {
+	values := a.lookup(n.name)
+	switch n.op {
+	case "==":
+		return slices.Contains(values, n.value)
+	case "!=":
+		return !slices.Contains(values, n.value)
+	default:
+		return len(values) > 0
+	}
+}
+
+type conditionParser struct {
+	tokens []string
+	pos    int
+}
+
+func parseCondition(expr string) (conditionNode, error) {
+	tokens, err := tokenizeCondition(expr)
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) == 0 {
+		return nil, fmt.Errorf("empty condition")
+	}
+
+	p := &conditionParser{tokens: tokens}
+	node, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.tokens) {
+		return nil, fmt.Errorf("unexpected %s", p.tokens[p.pos])
+	}
+	return node, nil
+}
+
+func (p *conditionParser) peek() string {
+	if p.pos < len(p.tokens) {
+		return p.tokens[p.pos]
+	}
+	return ""
+}
+
+func (p *conditionParser) next() string {
+	token := p.peek()
+	p.pos++
+	return token
+}
+
+func (p *conditionParser) parseOr() (conditionNode, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek() == "||" {
+		p.next()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = logicalNode{op: "||", left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *conditionParser) parseAnd() (conditionNode, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek() == "&&" {
+		p.next()
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = logicalNode{op: "&&", left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *conditionParser) parseUnary() (conditionNode, error) {
+	switch token := p.next(); {
+	case token == "!":
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return notNode{operand: operand}, nil
+	case token == "(":
+		node, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if p.next() != ")" {
+			return nil, fmt.Errorf("missing )")
+		}
+		return node, nil
+	case token == "profile" || token == "tag" || (strings.HasPrefix(token, "env.") && len(token) > len("env.")):
+		node := compareNode{name: token}
+		if op := p.peek(); op == "==" || op == "!=" {
+			p.next()
+			value := p.next()
+			if !isQuoted(value) {
+				return nil, fmt.Errorf("expected a quoted string after %s %s", token, op)
+			}
+			node.op, node.value = op, value[1:len(value)-1]
+		}
+		return node, nil
+	case token == "":
+		return nil, fmt.Errorf("unexpected end of condition")
+	default:
+		return nil, fmt.Errorf("unexpected %s, expected profile, tag or env.NAME", token)
+	}
+}
+
+func tokenizeCondition(expr string) ([]string, error) {
+	var tokens []string
+	for i := 0; i < len(expr); {
+		c := expr[i]
+		switch {
+		case c == ' ' || c == '\t':
+			i++
+		case strings.HasPrefix(expr[i:], "==") || strings.HasPrefix(expr[i:], "!=") ||
+			strings.HasPrefix(expr[i:], "&&") || strings.HasPrefix(expr[i:], "||"):
+			tokens = append(tokens, expr[i:i+2])
+			i += 2
+		case c == '!' || c == '(' || c == ')':
+			tokens = append(tokens, expr[i:i+1])
+			i++
+		case c == '\'' || c == '"':
+			end := strings.IndexByte(expr[i+1:], c)
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated string")
+			}
+			tokens = append(tokens, expr[i:i+end+2])
+			i += end + 2
+		case isIdentifierByte(c):
+			j := i
+			for j < len(expr) && isIdentifierByte(expr[j]) {
+				j++
+			}
+			tokens = append(tokens, expr[i:j])
+			i = j
+		default:
+			return nil, fmt.Errorf("unexpected character %q", c)
+		}
+	}
+	return tokens, nil
+}
+
+func isIdentifierByte(c byte) bool {
+	return c == '_' || c == '.' || c == '-' || ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z') || ('0' <= c && c <= '9')
+}
+
+func isQuoted(token string) bool {
+	return len(token) >= 2 && (token[0] == '\'' || token[0] == '"') && token[len(token)-1] == token[0]
+}
@@ -0,0 +1,127 @@
+package resolver
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestConditionMatches(t *testing.T) {
+	t.Setenv("FUSECTX_TEST_CI", "true")
+	t.Setenv("FUSECTX_TEST_EMPTY", "")
+
+	active := activation{profile: "ml", tags: []string{"gpu", "review"}}
+
+	tests := []struct {
+		name     string
+		when     string
+		expected bool
+		hasError bool
+	}{
+		{name: "profile equals", when: `"profile == 'ml'"`, expected: true},
+		{name: "profile differs", when: `"profile == 'web'"`, expected: false},
+		{name: "profile not equal", when: `"profile != 'web'"`, expected: true},
+		{name: "tag", when: `"tag == 'gpu'"`, expected: true},
+		{name: "missing tag", when: `"tag == 'onboarding'"`, expected: false},
+		{name: "env set", when: `"env.FUSECTX_TEST_CI"`, expected: true},
+		{name: "env empty", when: `"env.FUSECTX_TEST_EMPTY"`, expected: false},
+		{name: "env value", when: `"env.FUSECTX_TEST_CI == \"true\""`, expected: true},
+		{name: "negation", when: `"!env.FUSECTX_TEST_CI"`, expected: false},
+		{name: "precedence", when: `"profile == 'web' && tag == 'gpu' || tag == 'review'"`, expected: true},
+		{name: "parentheses", when: `"profile == 'web' && (tag == 'gpu' || tag == 'review')"`, expected: false},
+		{name: "mapping", when: `{profile: [web, ml], tag: gpu}`, expected: true},
+		{name: "mapping with unmatched key", when: `{profile: ml, env: FUSECTX_TEST_EMPTY}`, expected: false},
+		{name: "mapping with env", when: `{env: [FUSECTX_TEST_EMPTY, FUSECTX_TEST_CI]}`, expected: true},
+		{name: "unknown variable", when: `"team == 'ml'"`, hasError: true},
+		{name: "unquoted value", when: `"profile == ml"`, hasError: true},
+		{name: "unterminated string", when: `"profile == 'ml"`, hasError: true},
+		{name: "missing parenthesis", when: `"(profile == 'ml'"`, hasError: true},
+		{name: "trailing operator", when: `"profile == 'ml' &&"`, hasError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var condition Condition
+			err := yaml.Unmarshal([]byte(tt.when), &condition)
+			if tt.hasError {
+				if err == nil {
+					t.Error("expected error but got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if actual := active.matches(&condition); actual != tt.expected {
+				t.Errorf("expected %v, got %v", tt.expected, actual)
+			}
+		})
+	}
+}
+
+func TestConditionalIncludes(t *testing.T) {
+	fsys := fstest.MapFS{
+		"main.md": {Data: []byte(`---
+includes:
+  - path: gpu.md
+    when: "profile == 'ml'"
+  - path: review.md
+    when: {tag: [review, ci]}
+  - path: "!review.md"
+    when: "tag == 'quiet'"
+---
+# Main`)},
+		"gpu.md":    {Data: []byte("# GPU")},
+		"review.md": {Data: []byte("# Review")},
+		"invalid.md": {Data: []byte(`---
+includes:
+  - path: gpu.md
+    when: "profile = 'ml'"
+---
+# Invalid`)},
+	}
+
+	tests := []struct {
+		name     string
+		profile  string
+		tags     []string
+		expected string
+		chain    string
+	}{
+		{name: "no profile", expected: "# Main", chain: "main.md"},
+		{name: "profile", profile: "ml", expected: "# GPU\n\n# Main", chain: "main.md,gpu.md"},
+		{name: "tag", tags: []string{"ci"}, expected: "# Review\n\n# Main", chain: "main.md,review.md"},
+		{name: "conditional negation", tags: []string{"review", "quiet"}, expected: "# Main", chain: "main.md"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := Options{FS: fsys, Profile: tt.profile, Tags: tt.tags}
+			result, err := ResolveWithOptions("main.md", opts)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if result.Content != tt.expected {
+				t.Errorf("expected:\n%s\n\ngot:\n%s", tt.expected, result.Content)
+			}
+
+			chain, err := GetDependencyChainWithOptions("main.md", opts)
+			if err != nil {
+				t.Fatalf("unexpected error getting dependency chain: %v", err)
+			}
+			if strings.Join(chain, ",") != tt.chain {
+				t.Errorf("expected chain %s, got %v", tt.chain, chain)
+			}
+		})
+	}
+
+	_, err := ResolveWithOptions("invalid.md", Options{FS: fsys})
+	var frontmatterErr *FrontmatterError
+	if !errors.As(err, &frontmatterErr) || frontmatterErr.Line != 4 || frontmatterErr.Column != 11 {
+		t.Errorf("expected a FrontmatterError at invalid.md:4:11, got %v", err)
+	}
+}
@@ -0,0 +1,130 @@
+package resolver
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	yamlPositionPattern = regexp.MustCompile(`line (\d+)(?:, column (\d+))?: `)
+	yamlTagPattern      = regexp.MustCompile(`!!\w+`)
+)
+
+type CycleError struct {
+	Cycle []string
+}
+
+func (e *CycleError) Error() string {
+	return "circular dependency detected: " + strings.Join(e.Cycle, " -> ")
+}
+
+type MissingFileError struct {
+	Path     string
+	Referrer string
+	Line     int
+	Err      error
+}
+
+func (e *MissingFileError) Error() string {
+	if e.Referrer == "" {
+		return fmt.Sprintf("file %s does not exist", e.Path)
+	}
+	return fmt.Sprintf("%s:%d: file %s does not exist", e.Referrer, e.Line, e.Path)
+}
+
+func (e *MissingFileError) Unwrap() error {
+	return e.Err
+}
+
+type FrontmatterError struct {
+	Path    string
+	Line    int
+	Column  int
+	Message string
+	Err     error
+}
+
+func (e *FrontmatterError) Error() string {
+	location := e.Path
+	if e.Line > 0 {
+		if location == "" {
+			location = "line " + strconv.Itoa(e.Line)
+		} else {
+			location += ":" + strconv.Itoa(e.Line)
+		}
+		if e.Column > 0 {
+			location += ":" + strconv.Itoa(e.Column)
+		}
+	}
+	if location == "" {
+		return "invalid frontmatter: " + e.Message
+	}
+	return location + ": invalid frontmatter: " + e.Message
+}
+
+func (e *FrontmatterError) Unwrap() error {
+	return e.Err
+}
+
+type position struct {
+	file string
+	line int
+}
+
+func newCycleError(stack []string, path string) *CycleError {
+	start := slices.Index(stack, path)
+	if start < 0 {
+		start = 0
+	}
+	return &CycleError{Cycle: append(slices.Clone(stack[start:]), path)}
+}
+
+func openError(path string, from position, err error) error {
+	if errors.Is(err, fs.ErrNotExist) {
+		return &MissingFileError{Path: path, Referrer: from.file, Line: from.line, Err: err}
+	}
+	return fmt.Errorf("error opening file %s: %w", path, err)
+}
+
+func newFrontmatterError(err error, root *yaml.Node, lineOffset int) *FrontmatterError {
+	message := strings.TrimPrefix(err.Error(), "yaml: ")
+	var typeErr *yaml.TypeError
+	if errors.As(err, &typeErr) && len(typeErr.Errors) > 0 {
+		message = typeErr.Errors[0]
+	}
+
+	frontmatterErr := &FrontmatterError{Message: message, Err: err}
+	if m := yamlPositionPattern.FindStringSubmatchIndex(message); m != nil {
+		line, _ := strconv.Atoi(message[m[2]:m[3]])
+		frontmatterErr.Line = line + lineOffset
+		if m[4] >= 0 {
+			frontmatterErr.Column, _ = strconv.Atoi(message[m[4]:m[5]])
+		} else if root != nil {
+			frontmatterErr.Column = valueColumn(root, line, yamlTagPattern.FindString(message))
+		}
+		frontmatterErr.Message = message[:m[0]] + message[m[1]:]
+	}
+	return frontmatterErr
+}
+
+func valueColumn(node *yaml.Node, line int, tag string) int {
+	for i, child := range node.Content {
+		if node.Kind == yaml.MappingNode && i%2 == 0 {
+			continue
+		}
+		if child.Line == line && (tag == "" || child.ShortTag() == tag) {
+			return child.Column
+		}
+		if column := valueColumn(child, line, tag); column > 0 {
+			return column
+		}
+	}
+	return 0
+}
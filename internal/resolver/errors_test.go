@@ -0,0 +1,150 @@
+package resolver
+
+import (
+	"errors"
+	"io/fs"
+	"slices"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestCycleError(t *testing.T) {
+	fsys := fstest.MapFS{
+		"a.md": {Data: []byte("---\nextends: b.md\n---\nA")},
+		"b.md": {Data: []byte("---\nincludes:\n  - c.md\n---\nB")},
+		"c.md": {Data: []byte("---\nincludes:\n  - a.md\n---\nC")},
+	}
+
+	for _, resolve := range []func() error{
+		func() error {
+			_, err := ResolveWithOptions("a.md", Options{FS: fsys})
+			return err
+		},
+		func() error {
+			_, err := GetDependencyChainWithOptions("a.md", Options{FS: fsys})
+			return err
+		},
+	} {
+		var cycleErr *CycleError
+		if err := resolve(); !errors.As(err, &cycleErr) {
+			t.Fatalf("expected a CycleError, got %v", err)
+		}
+
+		expected := []string{"a.md", "b.md", "c.md", "a.md"}
+		if !slices.Equal(cycleErr.Cycle, expected) {
+			t.Errorf("expected cycle %v, got %v", expected, cycleErr.Cycle)
+		}
+		if !strings.Contains(cycleErr.Error(), "a.md -> b.md -> c.md -> a.md") {
+			t.Errorf("unexpected message %q", cycleErr.Error())
+		}
+	}
+}
+
+func TestMissingFileError(t *testing.T) {
+	fsys := fstest.MapFS{
+		"extends.md": {Data: []byte("---\nvars:\n  a: 1\nextends: missing.md\n---\nA")},
+		"include.md": {Data: []byte("---\nincludes:\n  - extends.md\n  - path: gone.md\n    priority: 2\n---\nB")},
+		"snippet.md": {Data: []byte("---\nincludes:\n  - main.go:1-5\n---\nC")},
+	}
+
+	tests := []struct {
+		file     string
+		path     string
+		referrer string
+		line     int
+	}{
+		{file: "extends.md", path: "missing.md", referrer: "extends.md", line: 4},
+		{file: "include.md", path: "missing.md", referrer: "extends.md", line: 4},
+		{file: "snippet.md", path: "main.go", referrer: "snippet.md", line: 3},
+		{file: "root.md", path: "root.md"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			_, err := ResolveWithOptions(tt.file, Options{FS: fsys})
+
+			var missingErr *MissingFileError
+			if !errors.As(err, &missingErr) {
+				t.Fatalf("expected a MissingFileError, got %v", err)
+			}
+			if missingErr.Path != tt.path || missingErr.Referrer != tt.referrer || missingErr.Line != tt.line {
+				t.Errorf("expected %s referenced at %s:%d, got %s referenced at %s:%d", tt.path, tt.referrer, tt.line, missingErr.Path, missingErr.Referrer, missingErr.Line)
+			}
+			if !errors.Is(err, fs.ErrNotExist) {
+				t.Error("expected the error to wrap fs.ErrNotExist")
+			}
+		})
+	}
+
+	_, err := ResolveWithOptions("include.md", Options{FS: fstest.MapFS{
+		"include.md": fsys["include.md"],
+		"extends.md": {Data: []byte("A")},
+	}})
+	var missingErr *MissingFileError
+	if !errors.As(err, &missingErr) || missingErr.Path != "gone.md" || missingErr.Line != 4 {
+		t.Errorf("expected gone.md referenced at line 4, got %v", err)
+	}
+}
+
+func TestFrontmatterError(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		line    int
+		column  int
+	}{
+		{
+			name:    "syntax error",
+			content: "---\nextends: base.md\n  includes: a.md\n---\nA",
+			line:    3,
+		},
+		{
+			name:    "type error",
+			content: "---\nextends: base.md\nmax_tokens: many\n---\nA",
+			line:    3,
+			column:  13,
+		},
+		{
+			name:    "type error in a list",
+			content: "---\nincludes:\n  - a.md\n  - path: [b.md]\n---\nA",
+			line:    4,
+			column:  11,
+		},
+		{
+			name:    "include without a path",
+			content: "---\nincludes:\n  - a.md\n  - {priority: 3}\n---\nA",
+			line:    4,
+			column:  5,
+		},
+		{
+			name:    "scalar frontmatter",
+			content: "---\nfoo\n---\nA",
+			line:    2,
+			column:  1,
+		},
+		{
+			name:    "list frontmatter",
+			content: "---\n- base.md\n---\nA",
+			line:    2,
+			column:  1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ResolveWithOptions("main.md", Options{FS: fstest.MapFS{"main.md": {Data: []byte(tt.content)}}})
+
+			var frontmatterErr *FrontmatterError
+			if !errors.As(err, &frontmatterErr) {
+				t.Fatalf("expected a FrontmatterError, got %v", err)
+			}
+			if frontmatterErr.Path != "main.md" || frontmatterErr.Line != tt.line || frontmatterErr.Column != tt.column {
+				t.Errorf("expected main.md:%d:%d, got %s:%d:%d (%s)", tt.line, tt.column, frontmatterErr.Path, frontmatterErr.Line, frontmatterErr.Column, frontmatterErr.Message)
+			}
+			if strings.Contains(frontmatterErr.Message, "line") || strings.Contains(frontmatterErr.Message, "plain") {
+				t.Errorf("expected the position to be removed from the message, got %q", frontmatterErr.Message)
+			}
+		})
+	}
+}
@@ -0,0 +1,72 @@
+package resolver
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+type fileSystem struct {
+	fsys fs.FS
+}
+
+func (f fileSystem) abs(p string) (string, error) {
+	if f.fsys == nil {
+		return filepath.Abs(p)
+	}
+
+	name := strings.TrimPrefix(path.Clean(filepath.ToSlash(p)), "/")
+	if name == "" {
+		name = "."
+	}
+	if !fs.ValidPath(name) {
+		return "", fmt.Errorf("path %s is outside the filesystem", p)
+	}
+	return name, nil
+}
+
+func (f fileSystem) dir(p string) string {
+	if f.fsys == nil {
+		return filepath.Dir(p)
+	}
+	return path.Dir(p)
+}
+
+func (f fileSystem) resolve(p, baseDir string) string {
+	if f.fsys == nil {
+		if filepath.IsAbs(p) {
+			return p
+		}
+		return filepath.Join(baseDir, p)
+	}
+
+	p = filepath.ToSlash(p)
+	if path.IsAbs(p) {
+		return strings.TrimPrefix(path.Clean(p), "/")
+	}
+	return path.Join(baseDir, p)
+}
+
+func (f fileSystem) open(name string) (fs.File, error) {
+	if f.fsys == nil {
+		return os.Open(name)
+	}
+	return f.fsys.Open(name)
+}
+
+func (f fileSystem) readFile(name string) ([]byte, error) {
+	if f.fsys == nil {
+		return os.ReadFile(name)
+	}
+	return fs.ReadFile(f.fsys, name)
+}
+
+func (f fileSystem) sub(dir string) (fs.FS, error) {
+	if f.fsys == nil {
+		return os.DirFS(dir), nil
+	}
+	return fs.Sub(f.fsys, dir)
+}
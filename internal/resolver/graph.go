@@ -0,0 +1,203 @@
+package resolver
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+type Graph struct {
+	Nodes []GraphNode `json:"nodes"`
+	Edges []GraphEdge `json:"edges"`
+}
+
+type GraphNode struct {
+	Path string `json:"path"`
+	Root bool   `json:"root,omitempty"`
+}
+
+type GraphEdge struct {
+	From     string `json:"from"`
+	To       string `json:"to"`
+	Relation string `json:"relation"`
+	Selector string `json:"selector,omitempty"`
+}
+
+type graphBuilder struct {
+	*resolution
+	graph  *Graph
+	nodes  map[string]int
+	edges  map[GraphEdge]bool
+	walked map[string]bool
+}
+
+func GetDependencyGraph(roots []string, opts Options) (*Graph, error) {
+	g := &graphBuilder{
+		resolution: newResolution(nil, opts),
+		graph:      &Graph{Nodes: []GraphNode{}, Edges: []GraphEdge{}},
+		nodes:      make(map[string]int),
+		edges:      make(map[GraphEdge]bool),
+		walked:     make(map[string]bool),
+	}
+
+	for _, root := range roots {
+		absPath, err := g.files.abs(root)
+		if err != nil {
+			return nil, fmt.Errorf("error resolving absolute path for %s: %w", root, err)
+		}
+		if err := g.walk(absPath, position{}); err != nil {
+			return nil, err
+		}
+		g.graph.Nodes[g.nodes[absPath]].Root = true
+	}
+
+	return g.graph, nil
+}
+
+func (g *graphBuilder) addNode(path string) {
+	if _, ok := g.nodes[path]; !ok {
+		g.nodes[path] = len(g.graph.Nodes)
+		g.graph.Nodes = append(g.graph.Nodes, GraphNode{Path: path})
+	}
+}
+
+func (g *graphBuilder) addEdge(edge GraphEdge) {
+	if !g.edges[edge] {
+		g.edges[edge] = true
+		g.graph.Edges = append(g.graph.Edges, edge)
+	}
+}
+
+func (g *graphBuilder) walk(absPath string, from position) error {
+	g.addNode(absPath)
+	if g.walked[absPath] {
+		return nil
+	}
+	g.walked[absPath] = true
+
+	frontmatter, _, err := g.parseFile(absPath, from, true)
+	if err != nil {
+		return err
+	}
+
+	for _, parent := range g.parents(frontmatter, absPath) {
+		g.addEdge(GraphEdge{From: absPath, To: parent.path, Relation: relationExtends})
+		if err := g.walk(parent.path, parent.from); err != nil {
+			return err
+		}
+	}
+
+	includes, err := g.expandIncludes(frontmatter.Includes, g.files.dir(absPath), absPath)
+	if err != nil {
+		return fmt.Errorf("error expanding includes in %s: %w", absPath, err)
+	}
+
+	for _, include := range includes {
+		g.addEdge(GraphEdge{
+			From:     absPath,
+			To:       include.Path,
+			Relation: relationInclude,
+			Selector: strings.TrimPrefix(include.String(), include.Path),
+		})
+		if include.isSnippet() {
+			g.addNode(include.Path)
+			continue
+		}
+		if err := g.walk(include.Path, include.from); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (g *Graph) DOT() string {
+	quote := func(s string) string {
+		return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+	}
+
+	var result strings.Builder
+	result.WriteString("digraph fusectx {\n")
+	result.WriteString("  node [shape=box];\n")
+	for _, node := range g.Nodes {
+		if node.Root {
+			fmt.Fprintf(&result, "  %s [style=bold];\n", quote(node.Path))
+		} else {
+			fmt.Fprintf(&result, "  %s;\n", quote(node.Path))
+		}
+	}
+	for _, edge := range g.Edges {
+		attributes := "label=" + quote(edge.label())
+		if edge.Relation == relationInclude {
+			attributes += ", style=dashed"
+		}
+		fmt.Fprintf(&result, "  %s -> %s [%s];\n", quote(edge.From), quote(edge.To), attributes)
+	}
+	result.WriteString("}\n")
+	return result.String()
+}
+
+func (g *Graph) Mermaid() string {
+	ids := make(map[string]string, len(g.Nodes))
+	quote := func(s string) string {
+		return `"` + strings.ReplaceAll(s, `"`, "#quot;") + `"`
+	}
+
+	var result strings.Builder
+	result.WriteString("flowchart TD\n")
+	var roots []string
+	for i, node := range g.Nodes {
+		ids[node.Path] = fmt.Sprintf("n%d", i)
+		fmt.Fprintf(&result, "  %s[%s]\n", ids[node.Path], quote(node.Path))
+		if node.Root {
+			roots = append(roots, ids[node.Path])
+		}
+	}
+	for _, edge := range g.Edges {
+		arrow := "-->"
+		if edge.Relation == relationInclude {
+			arrow = "-.->"
+		}
+		fmt.Fprintf(&result, "  %s %s|%s| %s\n", ids[edge.From], arrow, quote(edge.label()), ids[edge.To])
+	}
+	if len(roots) > 0 {
+		result.WriteString("  classDef root stroke-width:3px\n")
+		fmt.Fprintf(&result, "  class %s root\n", strings.Join(roots, ","))
+	}
+	return result.String()
+}
+
+func (e GraphEdge) label() string {
+	if e.Selector == "" {
+		return e.Relation
+	}
+	return e.Relation + " " + e.Selector
+}
+
+func (g *Graph) Dependents(path string) []string {
+	reverse := make(map[string][]string)
+	for _, edge := range g.Edges {
+		reverse[edge.To] = append(reverse[edge.To], edge.From)
+	}
+
+	reached := map[string]bool{path: true}
+	queue := []string{path}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		for _, from := range reverse[current] {
+			if !reached[from] {
+				reached[from] = true
+				queue = append(queue, from)
+			}
+		}
+	}
+
+	var dependents []string
+	for _, node := range g.Nodes {
+		if node.Root && reached[node.Path] && !slices.Contains(dependents, node.Path) {
+			dependents = append(dependents, node.Path)
+		}
+	}
+	return dependents
+}
@@ -0,0 +1,134 @@
+package resolver
+
+import (
+	"slices"
+	"testing"
+	"testing/fstest"
+)
+
+func TestGetDependencyGraph(t *testing.T) {
+	fsys := fstest.MapFS{
+		"base.md":       {Data: []byte("# Base")},
+		"policy.md":     {Data: []byte("---\nextends: base.md\n---\n# Policy")},
+		"a/fusectx.md":  {Data: []byte("---\nextends: [../base.md, ../policy.md]\nincludes:\n  - ../docs/guide.md#usage\n  - ../main.go:handler\n---\n# A")},
+		"b/fusectx.md":  {Data: []byte("---\nincludes:\n  - ../policy.md\n  - path: ../gpu.md\n    when: \"profile == 'ml'\"\n---\n# B")},
+		"docs/guide.md": {Data: []byte("# Guide\n\n## Usage\n\nRun it.")},
+		"main.go":       {Data: []byte("package main")},
+		"gpu.md":        {Data: []byte("# GPU")},
+		"cycle.md":      {Data: []byte("---\nincludes:\n  - cycle-b.md\n---\n# Cycle")},
+		"cycle-b.md":    {Data: []byte("---\nextends: cycle.md\n---\n# Cycle B")},
+	}
+
+	graph, err := GetDependencyGraph([]string{"a/fusectx.md", "b/fusectx.md"}, Options{FS: fsys})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expectedNodes := []GraphNode{
+		{Path: "a/fusectx.md", Root: true},
+		{Path: "base.md"},
+		{Path: "policy.md"},
+		{Path: "docs/guide.md"},
+		{Path: "main.go"},
+		{Path: "b/fusectx.md", Root: true},
+	}
+	if !slices.Equal(graph.Nodes, expectedNodes) {
+		t.Errorf("expected nodes %+v, got %+v", expectedNodes, graph.Nodes)
+	}
+
+	expectedEdges := []GraphEdge{
+		{From: "a/fusectx.md", To: "base.md", Relation: "extends"},
+		{From: "a/fusectx.md", To: "policy.md", Relation: "extends"},
+		{From: "policy.md", To: "base.md", Relation: "extends"},
+		{From: "a/fusectx.md", To: "docs/guide.md", Relation: "include", Selector: "#usage"},
+		{From: "a/fusectx.md", To: "main.go", Relation: "include", Selector: ":handler"},
+		{From: "b/fusectx.md", To: "policy.md", Relation: "include"},
+	}
+	if !slices.Equal(graph.Edges, expectedEdges) {
+		t.Errorf("expected edges %+v, got %+v", expectedEdges, graph.Edges)
+	}
+
+	graph, err = GetDependencyGraph([]string{"b/fusectx.md"}, Options{FS: fsys, Profile: "ml"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(graph.Edges) != 3 || graph.Edges[2].To != "gpu.md" {
+		t.Errorf("expected the conditional include to be followed, got %+v", graph.Edges)
+	}
+
+	graph, err = GetDependencyGraph([]string{"cycle.md"}, Options{FS: fsys})
+	if err != nil {
+		t.Fatalf("unexpected error for a cyclic graph: %v", err)
+	}
+	if len(graph.Nodes) != 2 || len(graph.Edges) != 2 {
+		t.Errorf("unexpected cyclic graph %+v", graph)
+	}
+
+	if _, err := GetDependencyGraph([]string{"missing.md"}, Options{FS: fsys}); err == nil {
+		t.Error("expected error for a missing file")
+	}
+}
+
+func TestGraphFormats(t *testing.T) {
+	graph := &Graph{
+		Nodes: []GraphNode{{Path: "main.md", Root: true}, {Path: "base.md"}, {Path: `say "hi".md`}},
+		Edges: []GraphEdge{
+			{From: "main.md", To: "base.md", Relation: "extends"},
+			{From: "main.md", To: `say "hi".md`, Relation: "include", Selector: "#intro"},
+		},
+	}
+
+	expectedDOT := `digraph fusectx {
+  node [shape=box];
+  "main.md" [style=bold];
+  "base.md";
+  "say \"hi\".md";
+  "main.md" -> "base.md" [label="extends"];
+  "main.md" -> "say \"hi\".md" [label="include #intro", style=dashed];
+}
+`
+	if dot := graph.DOT(); dot != expectedDOT {
+		t.Errorf("expected DOT:\n%s\ngot:\n%s", expectedDOT, dot)
+	}
+
+	expectedMermaid := `flowchart TD
+  n0["main.md"]
+  n1["base.md"]
+  n2["say #quot;hi#quot;.md"]
+  n0 -->|"extends"| n1
+  n0 -.->|"include #intro"| n2
+  classDef root stroke-width:3px
+  class n0 root
+`
+	if mermaid := graph.Mermaid(); mermaid != expectedMermaid {
+		t.Errorf("expected Mermaid:\n%s\ngot:\n%s", expectedMermaid, mermaid)
+	}
+}
+
+func TestGraphDependents(t *testing.T) {
+	fsys := fstest.MapFS{
+		"rules/security.md": {Data: []byte("# Security")},
+		"policy.md":         {Data: []byte("---\nincludes:\n  - rules/*.md\n---\n# Policy")},
+		"a/fusectx.md":      {Data: []byte("---\nextends: ../policy.md\n---\n# A")},
+		"b/fusectx.md":      {Data: []byte("---\nincludes:\n  - ../rules/security.md#intro\n---\n# B")},
+		"c/fusectx.md":      {Data: []byte("# C")},
+	}
+
+	graph, err := GetDependencyGraph([]string{"a/fusectx.md", "b/fusectx.md", "c/fusectx.md"}, Options{FS: fsys})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tests := map[string][]string{
+		"rules/security.md": {"a/fusectx.md", "b/fusectx.md"},
+		"policy.md":         {"a/fusectx.md"},
+		"c/fusectx.md":      {"c/fusectx.md"},
+		"unrelated.md":      nil,
+	}
+
+	for path, expected := range tests {
+		if actual := graph.Dependents(path); !slices.Equal(actual, expected) {
+			t.Errorf("Dependents(%q): expected %v, got %v", path, expected, actual)
+		}
+	}
+}
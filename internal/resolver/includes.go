@@ -0,0 +1,298 @@
+package resolver
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/bmatcuk/doublestar/v4"
+)
+
+const (
+	negationPrefix    = "!"
+	sectionSeparator  = "#"
+	selectorSeparator = ":"
+)
+
+var (
+	lineRangePattern  = regexp.MustCompile(`^(\d+)(?:-(\d+))?$`)
+	regionNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_.-]*$`)
+)
+
+type includeRef struct {
+	Path      string
+	Section   string
+	StartLine int
+	EndLine   int
+	Region    string
+	Priority  *int
+
+	from position
+}
+
+func (r includeRef) String() string {
+	switch {
+	case r.Section != "":
+		return r.Path + sectionSeparator + r.Section
+	case r.Region != "":
+		return r.Path + selectorSeparator + r.Region
+	case r.StartLine == r.EndLine && r.StartLine > 0:
+		return fmt.Sprintf("%s%s%d", r.Path, selectorSeparator, r.StartLine)
+	case r.StartLine > 0:
+		return fmt.Sprintf("%s%s%d-%d", r.Path, selectorSeparator, r.StartLine, r.EndLine)
+	}
+	return r.Path
+}
+
+func (r includeRef) isSnippet() bool {
+	return r.StartLine > 0 || r.Region != ""
+}
+
+func (r *resolution) resolveInclude(include includeRef) (*resolved, error) {
+	if include.Section == "" && !include.isSnippet() {
+		return r.resolveFile(include.Path, include.from)
+	}
+
+	duplicate := r.isEmitted(include.Path) || r.isEmitted(include.String())
+	r.markEmitted(include.String())
+
+	if include.isSnippet() {
+		if duplicate {
+			return &resolved{}, nil
+		}
+		snippet, err := r.readSnippet(include)
+		if err != nil {
+			return nil, err
+		}
+		return &resolved{segments: []segment{{
+			path:     include.String(),
+			relation: relationSelf,
+			content:  snippet,
+			verbatim: true,
+		}}}, nil
+	}
+
+	section := &resolution{visited: r.visited, stack: r.stack, maxDepth: r.maxDepth, files: r.files, active: r.active, cache: r.cache}
+	if r.emitted != nil {
+		section.emitted = make(map[string]bool)
+	}
+
+	res, err := section.resolveFile(include.Path, include.from)
+	if err != nil {
+		return nil, err
+	}
+
+	content, err := joinSegments(res.segments)
+	if err != nil {
+		return nil, err
+	}
+	content, err = extractSection(content, include.Section)
+	if err != nil {
+		return nil, fmt.Errorf("error reading %s: %w", include.Path, err)
+	}
+
+	verbatim := allVerbatim(res.segments)
+	res.segments = nil
+	if !duplicate {
+		res.segments = []segment{{path: include.String(), relation: relationSelf, content: content, verbatim: verbatim}}
+	}
+	return res, nil
+}
+
+func EntryContent(entry string) (string, error) {
+	return EntryContentWithOptions(entry, Options{})
+}
+
+func EntryContentWithOptions(entry string, opts Options) (string, error) {
+	return newResolution(nil, opts).entryContent(entry)
+}
+
+func EntryFile(entry string) string {
+	filePath, _, err := parseSelector(entry)
+	if err != nil {
+		return entry
+	}
+	return filePath
+}
+
+func (r *resolution) entryContent(entry string) (string, error) {
+	filePath, include, err := parseSelector(entry)
+	if err != nil {
+		return "", err
+	}
+	include.Path = filePath
+
+	if include.isSnippet() {
+		return r.readSnippet(include)
+	}
+	if include.Section != "" {
+		res, err := r.resolveInclude(include)
+		if err != nil {
+			return "", err
+		}
+		return joinSegments(res.segments)
+	}
+
+	_, content, err := r.parseFile(include.Path, position{}, true)
+	if err != nil {
+		return "", err
+	}
+
+	content, err = stripBlocks(content)
+	if err != nil {
+		return "", fmt.Errorf("error parsing blocks in %s: %w", include.Path, err)
+	}
+
+	return strings.TrimSpace(content), nil
+}
+
+func (r *resolution) expandIncludes(entries []Include, baseDir, self string) ([]includeRef, error) {
+	var includes []includeRef
+
+	for _, entry := range entries {
+		if !r.active.matches(entry.When) {
+			continue
+		}
+
+		if negated, ok := strings.CutPrefix(entry.Path, negationPrefix); ok {
+			var kept []includeRef
+			for _, include := range includes {
+				excluded, err := matchesPattern(include.Path, negated, baseDir)
+				if err != nil {
+					return nil, err
+				}
+				if !excluded {
+					kept = append(kept, include)
+				}
+			}
+			includes = kept
+			continue
+		}
+
+		pattern, selector, err := parseSelector(entry.Path)
+		if err != nil {
+			return nil, err
+		}
+		selector.Priority = entry.Priority
+		selector.from = position{self, entry.line}
+		paths, err := r.expandPattern(pattern, baseDir, self, includes)
+		if err != nil {
+			return nil, err
+		}
+		for _, p := range paths {
+			include := selector
+			include.Path = p
+			includes = append(includes, include)
+		}
+	}
+
+	return includes, nil
+}
+
+func parseSelector(entry string) (string, includeRef, error) {
+	if i := strings.LastIndex(entry, sectionSeparator); i > 0 && !strings.ContainsAny(entry[i:], `/\`) {
+		return entry[:i], includeRef{Section: entry[i+1:]}, nil
+	}
+
+	i := strings.LastIndex(entry, selectorSeparator)
+	if i <= 0 || strings.ContainsAny(entry[i:], `/\`) {
+		return entry, includeRef{}, nil
+	}
+
+	pattern, selector := entry[:i], entry[i+1:]
+
+	if m := lineRangePattern.FindStringSubmatch(selector); m != nil {
+		start, _ := strconv.Atoi(m[1])
+		end := start
+		if m[2] != "" {
+			end, _ = strconv.Atoi(m[2])
+		}
+		if start < 1 || end < start {
+			return "", includeRef{}, fmt.Errorf("invalid line range %q in include %s", selector, entry)
+		}
+		return pattern, includeRef{StartLine: start, EndLine: end}, nil
+	}
+
+	if regionNamePattern.MatchString(selector) {
+		return pattern, includeRef{Region: selector}, nil
+	}
+
+	return entry, includeRef{}, nil
+}
+
+func (r *resolution) expandPattern(pattern, baseDir, self string, listed []includeRef) ([]string, error) {
+	if !isGlobPattern(pattern) {
+		return []string{r.files.resolve(pattern, baseDir)}, nil
+	}
+
+	matches, err := r.globFiles(pattern, baseDir)
+	if err != nil {
+		return nil, err
+	}
+
+	seen := map[string]bool{self: true}
+	for _, include := range listed {
+		seen[include.Path] = true
+	}
+
+	var paths []string
+	for _, match := range matches {
+		if !seen[match] {
+			paths = append(paths, match)
+		}
+	}
+
+	return paths, nil
+}
+
+func isGlobPattern(pattern string) bool {
+	return strings.ContainsAny(pattern, "*?[{")
+}
+
+func (r *resolution) globFiles(pattern, baseDir string) ([]string, error) {
+	if !doublestar.ValidatePattern(filepath.ToSlash(pattern)) {
+		return nil, fmt.Errorf("invalid include pattern %q", pattern)
+	}
+
+	base, rest := doublestar.SplitPattern(filepath.ToSlash(pattern))
+	root := r.files.resolve(filepath.FromSlash(base), baseDir)
+
+	dir, err := r.files.sub(root)
+	if err != nil {
+		return nil, fmt.Errorf("error expanding include pattern %q: %w", pattern, err)
+	}
+	matches, err := doublestar.Glob(dir, rest, doublestar.WithFilesOnly())
+	if err != nil {
+		return nil, fmt.Errorf("error expanding include pattern %q: %w", pattern, err)
+	}
+
+	paths := make([]string, len(matches))
+	for i, match := range matches {
+		paths[i] = r.files.resolve(filepath.FromSlash(match), root)
+	}
+	sort.Strings(paths)
+
+	return paths, nil
+}
+
+func matchesPattern(p, pattern, baseDir string) (bool, error) {
+	pattern = path.Clean(filepath.ToSlash(pattern))
+	if !doublestar.ValidatePattern(pattern) {
+		return false, fmt.Errorf("invalid include pattern %q", negationPrefix+pattern)
+	}
+
+	name := p
+	if !path.IsAbs(pattern) {
+		rel, err := filepath.Rel(baseDir, p)
+		if err != nil {
+			return false, fmt.Errorf("error matching %s against %q: %w", p, negationPrefix+pattern, err)
+		}
+		name = rel
+	}
+
+	return doublestar.MatchUnvalidated(pattern, filepath.ToSlash(name)), nil
+}
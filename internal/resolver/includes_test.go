@@ -0,0 +1,219 @@
+package resolver
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestExpandIncludes(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "fusectx-includes-test")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	files := []string{
+		"main.md",
+		"intro.md",
+		"rules/b.md",
+		"rules/a.md",
+		"rules/draft-c.md",
+		"rules/notes.txt",
+		"docs/guide.md",
+		"docs/api/http.md",
+	}
+
+	for _, name := range files {
+		filePath := filepath.Join(tmpDir, name)
+		if err := os.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
+			t.Fatalf("failed to create dir for %s: %v", name, err)
+		}
+		if err := os.WriteFile(filePath, []byte("# "+name), 0644); err != nil {
+			t.Fatalf("failed to write test file %s: %v", name, err)
+		}
+	}
+
+	tests := []struct {
+		name     string
+		patterns []string
+		expected []string
+		hasError bool
+	}{
+		{
+			name:     "literal paths",
+			patterns: []string{"intro.md", "missing.md"},
+			expected: []string{"intro.md", "missing.md"},
+		},
+		{
+			name:     "single star is sorted",
+			patterns: []string{"rules/*.md"},
+			expected: []string{"rules/a.md", "rules/b.md", "rules/draft-c.md"},
+		},
+		{
+			name:     "double star",
+			patterns: []string{"docs/**/*.md"},
+			expected: []string{"docs/api/http.md", "docs/guide.md"},
+		},
+		{
+			name:     "negation",
+			patterns: []string{"rules/*.md", "!rules/draft-*.md"},
+			expected: []string{"rules/a.md", "rules/b.md"},
+		},
+		{
+			name:     "glob skips already listed files",
+			patterns: []string{"rules/b.md", "rules/*.md"},
+			expected: []string{"rules/b.md", "rules/a.md", "rules/draft-c.md"},
+		},
+		{
+			name:     "glob skips the including file",
+			patterns: []string{"*.md"},
+			expected: []string{"intro.md"},
+		},
+		{
+			name:     "glob without matches",
+			patterns: []string{"nothing/*.md"},
+			expected: nil,
+		},
+		{
+			name:     "section anchors",
+			patterns: []string{"docs/guide.md#testing", "rules/a*.md#usage"},
+			expected: []string{"docs/guide.md#testing", "rules/a.md#usage"},
+		},
+		{
+			name:     "negation ignores section anchors",
+			patterns: []string{"rules/*.md#usage", "!rules/[bd]*.md"},
+			expected: []string{"rules/a.md#usage"},
+		},
+		{
+			name:     "invalid pattern",
+			patterns: []string{"rules/[*.md"},
+			hasError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var entries []Include
+			for _, pattern := range tt.patterns {
+				entries = append(entries, Include{Path: pattern})
+			}
+
+			includes, err := (&resolution{}).expandIncludes(entries, tmpDir, filepath.Join(tmpDir, "main.md"))
+
+			if tt.hasError && err == nil {
+				t.Error("expected error but got none")
+			}
+			if !tt.hasError && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+
+			var actual []string
+			for _, include := range includes {
+				rel, err := filepath.Rel(tmpDir, include.String())
+				if err != nil {
+					t.Fatalf("failed to relativize %s: %v", include, err)
+				}
+				actual = append(actual, filepath.ToSlash(rel))
+			}
+
+			if strings.Join(actual, ",") != strings.Join(tt.expected, ",") {
+				t.Errorf("expected %v, got %v", tt.expected, actual)
+			}
+		})
+	}
+}
+
+func TestParseSelector(t *testing.T) {
+	tests := []struct {
+		entry    string
+		pattern  string
+		selector includeRef
+		hasError bool
+	}{
+		{entry: "guide.md", pattern: "guide.md"},
+		{entry: "guide.md#testing", pattern: "guide.md", selector: includeRef{Section: "testing"}},
+		{entry: "main.go:10-40", pattern: "main.go", selector: includeRef{StartLine: 10, EndLine: 40}},
+		{entry: "main.go:7", pattern: "main.go", selector: includeRef{StartLine: 7, EndLine: 7}},
+		{entry: "main.go:handler", pattern: "main.go", selector: includeRef{Region: "handler"}},
+		{entry: "src/*.go:setup", pattern: "src/*.go", selector: includeRef{Region: "setup"}},
+		{entry: "C:/docs/guide.md", pattern: "C:/docs/guide.md"},
+		{entry: "docs/c#/intro.md", pattern: "docs/c#/intro.md"},
+		{entry: "docs/c#/intro.md#setup", pattern: "docs/c#/intro.md", selector: includeRef{Section: "setup"}},
+		{entry: "main.go:40-10", hasError: true},
+		{entry: "main.go:0", hasError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.entry, func(t *testing.T) {
+			pattern, selector, err := parseSelector(tt.entry)
+
+			if tt.hasError && err == nil {
+				t.Error("expected error but got none")
+			}
+			if !tt.hasError && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+
+			if !tt.hasError && (pattern != tt.pattern || selector != tt.selector) {
+				t.Errorf("expected %q %+v, got %q %+v", tt.pattern, tt.selector, pattern, selector)
+			}
+		})
+	}
+}
+
+func TestEntryContent(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "fusectx-entry-test")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	files := map[string]string{
+		"guide.md": "---\nextends: base.md\n---\n# Guide\n\n## Testing\n\nRun tests.",
+		"base.md":  "# Base",
+		"main.go":  "package main\n\nfunc main() {}",
+	}
+
+	for filename, content := range files {
+		err := os.WriteFile(filepath.Join(tmpDir, filename), []byte(content), 0644)
+		if err != nil {
+			t.Fatalf("failed to write test file %s: %v", filename, err)
+		}
+	}
+
+	tests := map[string]string{
+		"guide.md":         "# Guide\n\n## Testing\n\nRun tests.",
+		"guide.md#testing": "## Testing\n\nRun tests.",
+		"main.go:3":        "```go\nfunc main() {}\n```",
+	}
+
+	for entry, expected := range tests {
+		t.Run(entry, func(t *testing.T) {
+			content, err := EntryContent(filepath.Join(tmpDir, entry))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if content != expected {
+				t.Errorf("expected %q, got %q", expected, content)
+			}
+		})
+	}
+}
+
+func TestEntryFile(t *testing.T) {
+	tests := map[string]string{
+		"docs/guide.md":         "docs/guide.md",
+		"docs/guide.md#testing": "docs/guide.md",
+		"main.go:3-5":           "main.go",
+		"main.go:handler":       "main.go",
+		"../dir:name/file.md":   "../dir:name/file.md",
+	}
+
+	for entry, expected := range tests {
+		if actual := EntryFile(entry); actual != expected {
+			t.Errorf("EntryFile(%q): expected %q, got %q", entry, expected, actual)
+		}
+	}
+}
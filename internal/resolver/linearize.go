@@ -0,0 +1,140 @@
+package resolver
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+type parentRef struct {
+	path string
+	from position
+}
+
+type ancestor struct {
+	path        string
+	depth       int
+	frontmatter *Frontmatter
+	content     string
+}
+
+func (r *resolution) parents(frontmatter *Frontmatter, absPath string) []parentRef {
+	var parents []parentRef
+	seen := make(map[string]bool)
+	for i, parent := range frontmatter.Extends {
+		if parent == "" {
+			continue
+		}
+		parentPath := r.files.resolve(parent, r.files.dir(absPath))
+		if seen[parentPath] {
+			continue
+		}
+		seen[parentPath] = true
+
+		from := position{file: absPath}
+		if i < len(frontmatter.extendsLines) {
+			from.line = frontmatter.extendsLines[i]
+		}
+		parents = append(parents, parentRef{path: parentPath, from: from})
+	}
+	return parents
+}
+
+func (r *resolution) linearize(absPath string, from position) ([]*ancestor, error) {
+	if r.maxDepth > 0 && len(r.visited) > r.maxDepth && !r.visited[absPath] {
+		return nil, fmt.Errorf("maximum depth of %d exceeded at %s", r.maxDepth, absPath)
+	}
+	leave, err := r.enter(absPath)
+	if err != nil {
+		return nil, err
+	}
+	defer leave()
+
+	frontmatter, content, err := r.parseFile(absPath, from, true)
+	if err != nil {
+		return nil, err
+	}
+
+	self := &ancestor{path: absPath, frontmatter: frontmatter, content: content}
+	parents := r.parents(frontmatter, absPath)
+	if len(parents) == 0 {
+		return []*ancestor{self}, nil
+	}
+
+	ancestors := make(map[string]*ancestor)
+	var sequences [][]string
+	for i := len(parents) - 1; i >= 0; i-- {
+		lineage, err := r.linearize(parents[i].path, parents[i].from)
+		if err != nil {
+			return nil, fmt.Errorf("error resolving extends file %s: %w", parents[i].path, err)
+		}
+
+		sequence := make([]string, len(lineage))
+		for j, a := range lineage {
+			sequence[len(lineage)-1-j] = a.path
+			if known, ok := ancestors[a.path]; !ok || a.depth+1 < known.depth {
+				nested := *a
+				nested.depth++
+				ancestors[a.path] = &nested
+			}
+		}
+		sequences = append(sequences, sequence)
+	}
+
+	direct := make([]string, len(parents))
+	for i, parent := range parents {
+		direct[len(parents)-1-i] = parent.path
+	}
+	sequences = append(sequences, direct)
+
+	merged, err := mergeLinearizations(sequences)
+	if err != nil {
+		return nil, fmt.Errorf("inconsistent extends hierarchy in %s: %w", absPath, err)
+	}
+
+	lineage := make([]*ancestor, 0, len(merged)+1)
+	for i := len(merged) - 1; i >= 0; i-- {
+		lineage = append(lineage, ancestors[merged[i]])
+	}
+	return append(lineage, self), nil
+}
+
+func mergeLinearizations(sequences [][]string) ([]string, error) {
+	var merged []string
+	for {
+		sequences = slices.DeleteFunc(sequences, func(sequence []string) bool {
+			return len(sequence) == 0
+		})
+		if len(sequences) == 0 {
+			return merged, nil
+		}
+
+		head := ""
+		for _, sequence := range sequences {
+			inTail := slices.ContainsFunc(sequences, func(other []string) bool {
+				return slices.Contains(other[1:], sequence[0])
+			})
+			if !inTail {
+				head = sequence[0]
+				break
+			}
+		}
+
+		if head == "" {
+			var heads []string
+			for _, sequence := range sequences {
+				if !slices.Contains(heads, sequence[0]) {
+					heads = append(heads, sequence[0])
+				}
+			}
+			return nil, fmt.Errorf("cannot order %s", strings.Join(heads, ", "))
+		}
+
+		merged = append(merged, head)
+		for i, sequence := range sequences {
+			if sequence[0] == head {
+				sequences[i] = sequence[1:]
+			}
+		}
+	}
+}
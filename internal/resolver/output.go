@@ -0,0 +1,217 @@
+package resolver
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"html"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+const (
+	AnnotateHTML    = "html"
+	AnnotateXML     = "xml"
+	AnnotateHeading = "heading"
+)
+
+const (
+	FormatText = "text"
+	FormatXML  = "xml"
+	FormatJSON = "json"
+)
+
+var xmlNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_.-]*$`)
+
+type XMLTags struct {
+	Documents string `yaml:"documents" json:"documents"`
+	Document  string `yaml:"document" json:"document"`
+	Source    string `yaml:"source" json:"source"`
+	Content   string `yaml:"content" json:"content"`
+}
+
+var defaultXMLTags = XMLTags{
+	Documents: "documents",
+	Document:  "document",
+	Source:    "source",
+	Content:   "document_content",
+}
+
+func (t *XMLTags) merge(other *XMLTags) {
+	if other == nil {
+		return
+	}
+	if other.Documents != "" {
+		t.Documents = other.Documents
+	}
+	if other.Document != "" {
+		t.Document = other.Document
+	}
+	if other.Source != "" {
+		t.Source = other.Source
+	}
+	if other.Content != "" {
+		t.Content = other.Content
+	}
+}
+
+func (r *resolved) tags() XMLTags {
+	tags := defaultXMLTags
+	tags.merge(&r.xmlTags)
+	return tags
+}
+
+func (t XMLTags) validate() error {
+	for _, name := range []string{t.Documents, t.Document, t.Source, t.Content} {
+		if !xmlNamePattern.MatchString(name) || strings.HasPrefix(strings.ToLower(name), "xml") {
+			return fmt.Errorf("invalid XML tag name %q", name)
+		}
+	}
+	return nil
+}
+
+func formatOutput(rendered []renderedSegment, opts Options, res *resolved, sourcePath string) (string, error) {
+	baseDir := filepath.Dir(sourcePath)
+
+	switch opts.Format {
+	case "", FormatText:
+		if opts.Annotate != "" {
+			return annotate(rendered, opts.Annotate, baseDir)
+		}
+		return joinRendered(rendered), nil
+	case FormatXML, FormatJSON:
+		if opts.Annotate != "" {
+			return "", fmt.Errorf("annotations are only supported by the %s format", FormatText)
+		}
+		if opts.Format == FormatJSON {
+			return formatJSON(newDocument(rendered, sourcePath, res))
+		}
+		return formatXML(rendered, res.tags(), baseDir)
+	default:
+		return "", fmt.Errorf("unknown format %q (expected %s, %s or %s)", opts.Format, FormatText, FormatXML, FormatJSON)
+	}
+}
+
+func formatXML(rendered []renderedSegment, tags XMLTags, baseDir string) (string, error) {
+	if err := tags.validate(); err != nil {
+		return "", err
+	}
+
+	var result strings.Builder
+	fmt.Fprintf(&result, "<%s>\n", tags.Documents)
+	for i, seg := range rendered {
+		fmt.Fprintf(&result, "<%s index=\"%d\">\n", tags.Document, i+1)
+		fmt.Fprintf(&result, "<%s>%s</%s>\n", tags.Source, escapeXML(relativeSource(seg.path, baseDir)), tags.Source)
+		fmt.Fprintf(&result, "<%s>\n%s\n</%s>\n", tags.Content, escapeXML(seg.text), tags.Content)
+		fmt.Fprintf(&result, "</%s>\n", tags.Document)
+	}
+	fmt.Fprintf(&result, "</%s>", tags.Documents)
+
+	return result.String(), nil
+}
+
+type Document struct {
+	Source      string              `json:"source"`
+	Frontmatter DocumentFrontmatter `json:"frontmatter"`
+	Segments    []DocumentSegment   `json:"segments"`
+	Content     string              `json:"content"`
+}
+
+type DocumentFrontmatter struct {
+	Vars      map[string]any `json:"vars"`
+	MaxTokens int            `json:"max_tokens,omitempty"`
+	XMLTags   XMLTags        `json:"xml_tags"`
+}
+
+type DocumentSegment struct {
+	Path     string `json:"path"`
+	Relation string `json:"relation"`
+	Depth    int    `json:"depth"`
+	Start    int    `json:"start"`
+	End      int    `json:"end"`
+	Hash     string `json:"hash"`
+	Content  string `json:"content"`
+}
+
+func newDocument(rendered []renderedSegment, sourcePath string, res *resolved) *Document {
+	baseDir := filepath.Dir(sourcePath)
+	doc := &Document{
+		Source: relativeSource(sourcePath, baseDir),
+		Frontmatter: DocumentFrontmatter{
+			Vars:      res.vars,
+			MaxTokens: res.maxTokens,
+			XMLTags:   res.tags(),
+		},
+		Segments: make([]DocumentSegment, len(rendered)),
+		Content:  joinRendered(rendered),
+	}
+
+	offset := 0
+	for i, seg := range rendered {
+		if i > 0 {
+			offset += len(segmentSeparator)
+		}
+		sum := sha256.Sum256([]byte(seg.text))
+		doc.Segments[i] = DocumentSegment{
+			Path:     relativeSource(seg.path, baseDir),
+			Relation: seg.relation,
+			Depth:    seg.depth,
+			Start:    offset,
+			End:      offset + len(seg.text),
+			Hash:     "sha256:" + hex.EncodeToString(sum[:]),
+			Content:  seg.text,
+		}
+		offset += len(seg.text)
+	}
+
+	return doc
+}
+
+func formatJSON(doc *Document) (string, error) {
+	var result bytes.Buffer
+	encoder := json.NewEncoder(&result)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(doc); err != nil {
+		return "", fmt.Errorf("error encoding JSON output: %w", err)
+	}
+	return strings.TrimSuffix(result.String(), "\n"), nil
+}
+
+var xmlEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+
+func escapeXML(text string) string {
+	return xmlEscaper.Replace(text)
+}
+
+func annotate(rendered []renderedSegment, style, baseDir string) (string, error) {
+	parts := make([]string, len(rendered))
+
+	for i, seg := range rendered {
+		source := relativeSource(seg.path, baseDir)
+
+		switch style {
+		case AnnotateHTML:
+			parts[i] = fmt.Sprintf("<!-- from: %s -->\n%s\n<!-- end: %s -->", source, seg.text, source)
+		case AnnotateXML:
+			parts[i] = fmt.Sprintf("<file path=\"%s\">\n%s\n</file>", html.EscapeString(source), seg.text)
+		case AnnotateHeading:
+			parts[i] = fmt.Sprintf("## Source: %s\n\n%s", source, seg.text)
+		default:
+			return "", fmt.Errorf("unknown annotation style %q (expected %s, %s or %s)", style, AnnotateHTML, AnnotateXML, AnnotateHeading)
+		}
+	}
+
+	return strings.Join(parts, segmentSeparator), nil
+}
+
+func relativeSource(path, baseDir string) string {
+	rel, err := filepath.Rel(baseDir, path)
+	if err != nil {
+		return path
+	}
+	return filepath.ToSlash(rel)
+}
@@ -0,0 +1,189 @@
+package resolver
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestAnnotate(t *testing.T) {
+	rendered := []renderedSegment{
+		{segment: segment{path: "/project/base.md"}, text: "# Base"},
+		{segment: segment{path: "/project/rules/a&b.md#testing"}, text: "## Testing"},
+	}
+
+	tests := []struct {
+		style    string
+		expected string
+		hasError bool
+	}{
+		{
+			style:    AnnotateHTML,
+			expected: "<!-- from: base.md -->\n# Base\n<!-- end: base.md -->\n\n<!-- from: rules/a&b.md#testing -->\n## Testing\n<!-- end: rules/a&b.md#testing -->",
+		},
+		{
+			style:    AnnotateXML,
+			expected: "<file path=\"base.md\">\n# Base\n</file>\n\n<file path=\"rules/a&amp;b.md#testing\">\n## Testing\n</file>",
+		},
+		{
+			style:    AnnotateHeading,
+			expected: "## Source: base.md\n\n# Base\n\n## Source: rules/a&b.md#testing\n\n## Testing",
+		},
+		{
+			style:    "yaml",
+			hasError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.style, func(t *testing.T) {
+			result, err := annotate(rendered, tt.style, "/project")
+
+			if tt.hasError && err == nil {
+				t.Error("expected error but got none")
+			}
+			if !tt.hasError && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+
+			if !tt.hasError && result != tt.expected {
+				t.Errorf("expected:\n%s\n\ngot:\n%s", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestFormatXML(t *testing.T) {
+	rendered := []renderedSegment{
+		{segment: segment{path: "/project/base.md"}, text: "# Base\nUse <T> & generics"},
+		{segment: segment{path: "/project/main.md"}, text: "# Main"},
+	}
+
+	tests := []struct {
+		name     string
+		tags     XMLTags
+		expected string
+		hasError bool
+	}{
+		{
+			name: "default tags",
+			tags: defaultXMLTags,
+			expected: `<documents>
+<document index="1">
+<source>base.md</source>
+<document_content>
+# Base
+Use &lt;T&gt; &amp; generics
+</document_content>
+</document>
+<document index="2">
+<source>main.md</source>
+<document_content>
+# Main
+</document_content>
+</document>
+</documents>`,
+		},
+		{
+			name: "custom tags",
+			tags: XMLTags{Documents: "context", Document: "file", Source: "path", Content: "body"},
+			expected: `<context>
+<file index="1">
+<path>base.md</path>
+<body>
+# Base
+Use &lt;T&gt; &amp; generics
+</body>
+</file>
+<file index="2">
+<path>main.md</path>
+<body>
+# Main
+</body>
+</file>
+</context>`,
+		},
+		{
+			name:     "invalid tag name",
+			tags:     XMLTags{Documents: "my docs", Document: "document", Source: "source", Content: "content"},
+			hasError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := formatXML(rendered, tt.tags, "/project")
+
+			if tt.hasError && err == nil {
+				t.Error("expected error but got none")
+			}
+			if !tt.hasError && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+
+			if !tt.hasError && result != tt.expected {
+				t.Errorf("expected:\n%s\n\ngot:\n%s", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestXMLTagsMerge(t *testing.T) {
+	tags := defaultXMLTags
+	tags.merge(nil)
+	tags.merge(&XMLTags{Document: "doc"})
+
+	expected := XMLTags{Documents: "documents", Document: "doc", Source: "source", Content: "document_content"}
+	if tags != expected {
+		t.Errorf("expected %+v, got %+v", expected, tags)
+	}
+}
+
+func TestFormatJSON(t *testing.T) {
+	rendered := []renderedSegment{
+		{segment: segment{path: "/project/base.md", relation: relationExtends, depth: 1}, text: "# Base <b>"},
+		{segment: segment{path: "/project/rules/go.md#testing", relation: relationInclude, depth: 1}, text: "## Testing"},
+		{segment: segment{path: "/project/main.md", relation: relationSelf}, text: "# Main"},
+	}
+	res := &resolved{vars: map[string]any{"service": "billing"}, maxTokens: 100}
+
+	output, err := formatJSON(newDocument(rendered, "/project/main.md", res))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var doc Document
+	if err := json.Unmarshal([]byte(output), &doc); err != nil {
+		t.Fatalf("invalid JSON: %v\n%s", err, output)
+	}
+
+	if doc.Source != "main.md" {
+		t.Errorf("expected source main.md, got %q", doc.Source)
+	}
+	if doc.Content != "# Base <b>\n\n## Testing\n\n# Main" {
+		t.Errorf("unexpected content %q", doc.Content)
+	}
+	if doc.Frontmatter.Vars["service"] != "billing" || doc.Frontmatter.MaxTokens != 100 {
+		t.Errorf("unexpected frontmatter %+v", doc.Frontmatter)
+	}
+
+	expected := []DocumentSegment{
+		{Path: "base.md", Relation: "extends", Depth: 1, Start: 0, End: 10, Content: "# Base <b>"},
+		{Path: "rules/go.md#testing", Relation: "include", Depth: 1, Start: 12, End: 22, Content: "## Testing"},
+		{Path: "main.md", Relation: "self", Depth: 0, Start: 24, End: 30, Content: "# Main"},
+	}
+	if len(doc.Segments) != len(expected) {
+		t.Fatalf("expected %d segments, got %d", len(expected), len(doc.Segments))
+	}
+	for i, seg := range doc.Segments {
+		if len(seg.Hash) != len("sha256:")+64 {
+			t.Errorf("unexpected hash %q", seg.Hash)
+		}
+		seg.Hash = ""
+		if seg != expected[i] {
+			t.Errorf("expected segment %+v, got %+v", expected[i], seg)
+		}
+		if doc.Content[seg.Start:seg.End] != seg.Content {
+			t.Errorf("offsets of %s do not match its content", seg.Path)
+		}
+	}
+}
@@ -4,21 +4,100 @@ import (
 	"bufio"
 	"fmt"
 	"io"
-	"os"
-	"path/filepath"
+	"io/fs"
+	"reflect"
 	"strings"
 
+	"github.com/hbelmiro/fusectx/internal/tokenizer"
 	"gopkg.in/yaml.v3"
 )
 
 type Frontmatter struct {
-	Extends  string   `yaml:"extends"`
-	Includes []string `yaml:"includes"`
+	Extends   StringList     `yaml:"extends"`
+	Includes  []Include      `yaml:"includes"`
+	Vars      map[string]any `yaml:"vars"`
+	MaxTokens int            `yaml:"max_tokens"`
+	XMLTags   *XMLTags       `yaml:"xml_tags"`
+	Verbatim  bool           `yaml:"verbatim"`
+
+	extendsLines []int
+	unknownKeys  []*FrontmatterError
+	contentLine  int
+}
+
+func (f *Frontmatter) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind != yaml.MappingNode && node.Tag != "!!null" {
+		return fmt.Errorf("line %d, column %d: frontmatter must be a mapping", node.Line, node.Column)
+	}
+
+	type plain Frontmatter
+	if err := node.Decode((*plain)(f)); err != nil {
+		return err
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value != "extends" {
+			continue
+		}
+		value := node.Content[i+1]
+		if value.Kind != yaml.SequenceNode {
+			f.extendsLines = []int{value.Line}
+			continue
+		}
+		f.extendsLines = nil
+		for _, item := range value.Content {
+			f.extendsLines = append(f.extendsLines, item.Line)
+		}
+	}
+	return nil
+}
+
+type StringList []string
+
+func (l *StringList) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		var value string
+		if err := node.Decode(&value); err != nil {
+			return err
+		}
+		*l = nil
+		if value != "" {
+			*l = StringList{value}
+		}
+		return nil
+	}
+	return node.Decode((*[]string)(l))
+}
+
+type Include struct {
+	Path     string     `yaml:"path"`
+	Priority *int       `yaml:"priority"`
+	When     *Condition `yaml:"when"`
+
+	line int
+}
+
+func (i *Include) UnmarshalYAML(node *yaml.Node) error {
+	i.line = node.Line
+	if node.Kind == yaml.ScalarNode {
+		return node.Decode(&i.Path)
+	}
+	type plain Include
+	if err := node.Decode((*plain)(i)); err != nil {
+		return err
+	}
+	if i.Path == "" {
+		return fmt.Errorf("line %d, column %d: include entry requires a path", node.Line, node.Column)
+	}
+	return nil
 }
 
 const frontmatterSeparator = "---"
 
 func ParseFrontmatter(reader io.Reader) (*Frontmatter, string, error) {
+	return parseFrontmatter(reader, true)
+}
+
+func parseFrontmatter(reader io.Reader, strict bool) (*Frontmatter, string, error) {
 	scanner := bufio.NewScanner(reader)
 	var lines []string
 	var inFrontmatter bool
@@ -54,136 +133,398 @@ func ParseFrontmatter(reader io.Reader) (*Frontmatter, string, error) {
 	var frontmatter Frontmatter
 	if len(frontmatterLines) > 0 {
 		frontmatterContent := strings.Join(frontmatterLines, "\n")
-		if err := yaml.Unmarshal([]byte(frontmatterContent), &frontmatter); err != nil {
-			return nil, "", fmt.Errorf("error parsing frontmatter: %w", err)
+		var node yaml.Node
+		if err := yaml.Unmarshal([]byte(frontmatterContent), &node); err != nil {
+			return nil, "", newFrontmatterError(err, nil, 1)
+		}
+		if node.Kind != 0 {
+			if err := node.Decode(&frontmatter); err != nil {
+				return nil, "", newFrontmatterError(err, &node, 1)
+			}
+			frontmatter.unknownKeys = unknownKeys(node.Content[0], reflect.TypeFor[Frontmatter]())
+		}
+		for i := range frontmatter.extendsLines {
+			frontmatter.extendsLines[i]++
+		}
+		for i := range frontmatter.Includes {
+			frontmatter.Includes[i].line++
+		}
+		for _, unknown := range frontmatter.unknownKeys {
+			unknown.Line++
+		}
+		if strict && len(frontmatter.unknownKeys) > 0 {
+			return nil, "", frontmatter.unknownKeys[0]
 		}
 	}
 
+	frontmatter.contentLine = len(lines) - len(contentLines) + 1
 	content := strings.Join(contentLines, "\n")
 	return &frontmatter, content, nil
 }
 
-func Resolve(filePath string, visited map[string]bool) (string, error) {
+type Options struct {
+	Vars            map[string]string
+	AllowDuplicates bool
+	MaxTokens       int
+	Strict          bool
+	Tokenizer       tokenizer.Tokenizer
+	Annotate        string
+	Format          string
+	MaxDepth        int
+	Transforms      []Transform
+	FS              fs.FS
+	Profile         string
+	Tags            []string
+	Cache           *ParseCache
+}
+
+type Transform func(path, content string) (string, error)
+
+type Result struct {
+	Content  string
+	Document *Document
+	Cuts     []Cut
+}
+
+type resolution struct {
+	visited  map[string]bool
+	stack    []string
+	emitted  map[string]bool
+	maxDepth int
+	files    fileSystem
+	active   activation
+	cache    *ParseCache
+}
+
+type resolved struct {
+	segments  []segment
+	vars      map[string]any
+	maxTokens int
+	xmlTags   XMLTags
+}
+
+func newResolution(visited map[string]bool, opts Options) *resolution {
 	if visited == nil {
 		visited = make(map[string]bool)
 	}
+	r := &resolution{
+		visited:  visited,
+		maxDepth: opts.MaxDepth,
+		files:    fileSystem{opts.FS},
+		active:   activation{profile: opts.Profile, tags: opts.Tags},
+		cache:    opts.Cache,
+	}
+	if !opts.AllowDuplicates {
+		r.emitted = make(map[string]bool)
+	}
+	return r
+}
 
-	absPath, err := filepath.Abs(filePath)
+func Resolve(filePath string, visited map[string]bool) (string, error) {
+	res, err := newResolution(visited, Options{}).resolveFile(filePath, position{})
 	if err != nil {
-		return "", fmt.Errorf("error resolving absolute path for %s: %w", filePath, err)
+		return "", err
 	}
+	return render(res.segments, res.vars, nil)
+}
 
-	if visited[absPath] {
-		return "", fmt.Errorf("circular dependency detected: %s", absPath)
+func ResolveWithOptions(filePath string, opts Options) (*Result, error) {
+	r := newResolution(nil, opts)
+	absPath, err := r.files.abs(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("error resolving absolute path for %s: %w", filePath, err)
+	}
+
+	res, err := r.resolveFile(absPath, position{})
+	if err != nil {
+		return nil, err
+	}
+	for key, value := range opts.Vars {
+		res.vars[key] = value
+	}
+
+	output := func(segments []segment) (string, error) {
+		rendered, err := renderSegments(segments, res.vars, opts.Transforms)
+		if err != nil {
+			return "", err
+		}
+		return formatOutput(rendered, opts, res, absPath)
+	}
+	measured := output
+	if opts.Format == FormatJSON {
+		measured = func(segments []segment) (string, error) {
+			return render(segments, res.vars, opts.Transforms)
+		}
+	}
+
+	segments := res.segments
+	var cuts []Cut
+
+	maxTokens := opts.MaxTokens
+	if maxTokens == 0 {
+		maxTokens = res.maxTokens
+	}
+	if maxTokens > 0 {
+		tok := opts.Tokenizer
+		if tok == nil {
+			tok = tokenizer.Heuristic{}
+		}
+		segments, cuts, err = fitBudget(segments, measured, maxTokens, tok, opts.Strict)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	rendered, err := renderSegments(segments, res.vars, opts.Transforms)
+	if err != nil {
+		return nil, err
 	}
+	content, err := formatOutput(rendered, opts, res, absPath)
+	if err != nil {
+		return nil, err
+	}
+	return &Result{Content: content, Document: newDocument(rendered, absPath, res), Cuts: cuts}, nil
+}
 
-	visited[absPath] = true
-	defer func() { delete(visited, absPath) }()
+func (r *resolution) resolveFile(filePath string, from position) (*resolved, error) {
+	absPath, err := r.files.abs(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("error resolving absolute path for %s: %w", filePath, err)
+	}
 
-	file, err := os.Open(absPath)
+	lineage, err := r.linearize(absPath, from)
 	if err != nil {
-		return "", fmt.Errorf("error opening file %s: %w", absPath, err)
+		return nil, err
 	}
-	defer file.Close()
 
-	frontmatter, content, err := ParseFrontmatter(file)
+	leave, err := r.enter(absPath)
 	if err != nil {
-		return "", fmt.Errorf("error parsing file %s: %w", absPath, err)
+		return nil, err
+	}
+	defer leave()
+
+	duplicate := r.isEmitted(absPath)
+
+	result := &resolved{vars: make(map[string]any)}
+	strictBlocks := true
+	for _, a := range lineage {
+		skipped := a.depth > 0 && r.isEmitted(a.path)
+		r.markEmitted(a.path)
+		if err := r.extend(result, a, skipped, strictBlocks); err != nil {
+			return nil, err
+		}
+		if skipped {
+			strictBlocks = false
+		}
+	}
+
+	if duplicate {
+		result.segments = nil
+	}
+	return result, nil
+}
+
+func (r *resolution) extend(result *resolved, a *ancestor, skipped, strictBlocks bool) error {
+	if a.depth > 0 {
+		leave, err := r.enter(a.path)
+		if err != nil {
+			return err
+		}
+		defer leave()
 	}
 
-	var result strings.Builder
+	var ownBlocks []*blockNode
+	if !skipped {
+		var parentBlocks [][]*blockNode
+		blockIndex := make(map[string][]*blockNode)
+		for _, seg := range result.segments {
+			blocks, err := parseBlocks(seg.content)
+			if err != nil {
+				return fmt.Errorf("error parsing blocks of extends file %s: %w", seg.path, err)
+			}
+			parentBlocks = append(parentBlocks, blocks)
+			indexBlocks(blocks, blockIndex)
+		}
 
-	if frontmatter.Extends != "" {
-		extendsPath := resolvePath(frontmatter.Extends, filepath.Dir(absPath))
-		extendsContent, err := Resolve(extendsPath, visited)
+		blocks, err := parseBlocks(a.content)
+		if err != nil {
+			return fmt.Errorf("error parsing blocks in %s: %w", a.path, err)
+		}
+		ownBlocks, err = overrideBlocks(blockIndex, blocks, strictBlocks)
 		if err != nil {
-			return "", fmt.Errorf("error resolving extends file %s: %w", extendsPath, err)
+			return fmt.Errorf("error overriding blocks in %s: %w", a.path, err)
 		}
-		if extendsContent != "" {
-			result.WriteString(extendsContent)
-			result.WriteString("\n\n")
+		for i, blocks := range parentBlocks {
+			result.segments[i].content = renderBlocks(blocks, true)
 		}
 	}
 
-	for _, includePath := range frontmatter.Includes {
-		includeFullPath := resolvePath(includePath, filepath.Dir(absPath))
-		includeContent, err := Resolve(includeFullPath, visited)
+	includes, err := r.expandIncludes(a.frontmatter.Includes, r.files.dir(a.path), a.path)
+	if err != nil {
+		return fmt.Errorf("error expanding includes in %s: %w", a.path, err)
+	}
+
+	for _, include := range includes {
+		included, err := r.resolveInclude(include)
 		if err != nil {
-			return "", fmt.Errorf("error resolving include file %s: %w", includeFullPath, err)
+			return fmt.Errorf("error resolving include file %s: %w", include, err)
+		}
+		mergeVars(result.vars, included.vars)
+		if skipped {
+			continue
 		}
-		if includeContent != "" {
-			result.WriteString(includeContent)
-			result.WriteString("\n\n")
+		for _, seg := range nest(included.segments, relationInclude) {
+			seg.included = true
+			seg.depth += a.depth
+			if seg.priority == nil {
+				seg.priority = include.Priority
+			}
+			result.segments = append(result.segments, seg)
 		}
 	}
 
-	if content != "" {
-		result.WriteString(content)
+	if !skipped {
+		relation := relationSelf
+		if a.depth > 0 {
+			relation = relationExtends
+		}
+		result.segments = append(result.segments, segment{
+			path:     a.path,
+			relation: relation,
+			depth:    a.depth,
+			content:  renderBlocks(ownBlocks, true),
+			line:     a.frontmatter.contentLine,
+			verbatim: a.frontmatter.Verbatim,
+		})
 	}
 
-	return strings.TrimSpace(result.String()), nil
+	mergeVars(result.vars, a.frontmatter.Vars)
+	if a.frontmatter.MaxTokens > 0 {
+		result.maxTokens = a.frontmatter.MaxTokens
+	}
+	result.xmlTags.merge(a.frontmatter.XMLTags)
+	return nil
 }
 
-func resolvePath(path, basePath string) string {
-	if filepath.IsAbs(path) {
-		return path
+func (r *resolution) enter(absPath string) (func(), error) {
+	if r.visited[absPath] {
+		return nil, newCycleError(r.stack, absPath)
 	}
-	return filepath.Join(basePath, path)
+
+	r.visited[absPath] = true
+	r.stack = append(r.stack, absPath)
+	return func() {
+		delete(r.visited, absPath)
+		r.stack = r.stack[:len(r.stack)-1]
+	}, nil
 }
 
-func ValidateChain(filePath string) error {
-	_, err := Resolve(filePath, nil)
-	return err
+func (r *resolution) parseFile(absPath string, from position, strict bool) (*Frontmatter, string, error) {
+	parsed := r.cache.load(r.files, absPath)
+	if parsed.openErr != nil {
+		return nil, "", openError(absPath, from, parsed.openErr)
+	}
+	if parsed.err != nil {
+		return nil, "", parsed.err
+	}
+	if strict && len(parsed.frontmatter.unknownKeys) > 0 {
+		return nil, "", parsed.frontmatter.unknownKeys[0]
+	}
+	return parsed.frontmatter, parsed.content, nil
 }
 
-func GetDependencyChain(filePath string, visited map[string]bool) ([]string, error) {
-	if visited == nil {
-		visited = make(map[string]bool)
+func (r *resolution) isEmitted(key string) bool {
+	return r.emitted != nil && r.emitted[key]
+}
+
+func (r *resolution) markEmitted(key string) {
+	if r.emitted != nil {
+		r.emitted[key] = true
 	}
+}
+
+func GetDependencyChain(filePath string, visited map[string]bool) ([]string, error) {
+	return newResolution(visited, Options{}).dependencyChain(filePath, position{})
+}
+
+func GetDependencyChainWithOptions(filePath string, opts Options) ([]string, error) {
+	return newResolution(nil, opts).dependencyChain(filePath, position{})
+}
 
-	absPath, err := filepath.Abs(filePath)
+func (r *resolution) dependencyChain(filePath string, from position) ([]string, error) {
+	absPath, err := r.files.abs(filePath)
 	if err != nil {
 		return nil, fmt.Errorf("error resolving absolute path for %s: %w", filePath, err)
 	}
 
-	if visited[absPath] {
-		return nil, fmt.Errorf("circular dependency detected: %s", absPath)
+	lineage, err := r.linearize(absPath, from)
+	if err != nil {
+		return nil, err
 	}
 
-	visited[absPath] = true
-	defer func() { delete(visited, absPath) }()
-
-	var chain []string
-	chain = append(chain, absPath)
-
-	file, err := os.Open(absPath)
+	leave, err := r.enter(absPath)
 	if err != nil {
-		return nil, fmt.Errorf("error opening file %s: %w", absPath, err)
+		return nil, err
 	}
-	defer file.Close()
+	defer leave()
 
-	frontmatter, _, err := ParseFrontmatter(file)
-	if err != nil {
-		return nil, fmt.Errorf("error parsing file %s: %w", absPath, err)
+	var chain []string
+	for _, a := range lineage {
+		ancestorChain, err := r.ancestorChain(a)
+		if err != nil {
+			return nil, err
+		}
+		chain = append(chain, ancestorChain...)
 	}
 
-	if frontmatter.Extends != "" {
-		extendsPath := resolvePath(frontmatter.Extends, filepath.Dir(absPath))
-		extendsChain, err := GetDependencyChain(extendsPath, visited)
+	return uniquePaths(chain), nil
+}
+
+func (r *resolution) ancestorChain(a *ancestor) ([]string, error) {
+	if a.depth > 0 {
+		leave, err := r.enter(a.path)
 		if err != nil {
 			return nil, err
 		}
-		chain = append(extendsChain, chain...)
+		defer leave()
 	}
 
-	for _, includePath := range frontmatter.Includes {
-		includeFullPath := resolvePath(includePath, filepath.Dir(absPath))
-		includeChain, err := GetDependencyChain(includeFullPath, visited)
+	chain := []string{a.path}
+
+	includes, err := r.expandIncludes(a.frontmatter.Includes, r.files.dir(a.path), a.path)
+	if err != nil {
+		return nil, fmt.Errorf("error expanding includes in %s: %w", a.path, err)
+	}
+
+	for _, include := range includes {
+		if include.isSnippet() {
+			chain = append(chain, include.String())
+			continue
+		}
+
+		includeChain, err := r.dependencyChain(include.Path, include.from)
 		if err != nil {
 			return nil, err
 		}
+		for i, path := range includeChain {
+			if path == include.Path {
+				includeChain[i] = include.String()
+			}
+		}
 		chain = append(chain, includeChain...)
 	}
 
 	return chain, nil
-}
\ No newline at end of file
+}
+
+func uniquePaths(paths []string) []string {
+	seen := make(map[string]bool)
+	var unique []string
+	for _, p := range paths {
+		if !seen[p] {
+			seen[p] = true
+			unique = append(unique, p)
+		}
+	}
+	return unique
+}
@@ -1,10 +1,13 @@
 package resolver
 
 import (
+	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 	"testing"
+	"testing/fstest"
 )
 
 func TestParseFrontmatter(t *testing.T) {
@@ -30,7 +33,7 @@ extends: base.md
 ---
 # Header
 Content here`,
-			expectedFM:      Frontmatter{Extends: "base.md"},
+			expectedFM:      Frontmatter{Extends: StringList{"base.md"}},
 			expectedContent: "# Header\nContent here",
 			shouldError:     false,
 		},
@@ -42,7 +45,7 @@ includes:
   - file2.md
 ---
 Content`,
-			expectedFM:      Frontmatter{Includes: []string{"file1.md", "file2.md"}},
+			expectedFM:      Frontmatter{Includes: []Include{{Path: "file1.md"}, {Path: "file2.md"}}},
 			expectedContent: "Content",
 			shouldError:     false,
 		},
@@ -56,8 +59,65 @@ includes:
 ---
 Content`,
 			expectedFM: Frontmatter{
-				Extends:  "base.md",
-				Includes: []string{"file1.md", "file2.md"},
+				Extends:  StringList{"base.md"},
+				Includes: []Include{{Path: "file1.md"}, {Path: "file2.md"}},
+			},
+			expectedContent: "Content",
+			shouldError:     false,
+		},
+		{
+			name: "frontmatter with a list of parents",
+			input: `---
+extends:
+  - base.md
+  - policy.md
+---
+Content`,
+			expectedFM:      Frontmatter{Extends: StringList{"base.md", "policy.md"}},
+			expectedContent: "Content",
+			shouldError:     false,
+		},
+		{
+			name: "frontmatter with include priorities and budget",
+			input: `---
+includes:
+  - file1.md
+  - path: file2.md
+    priority: 5
+max_tokens: 2000
+---
+Content`,
+			expectedFM: Frontmatter{
+				Includes:  []Include{{Path: "file1.md"}, {Path: "file2.md"}},
+				MaxTokens: 2000,
+			},
+			expectedContent: "Content",
+			shouldError:     false,
+		},
+		{
+			name: "frontmatter with vars",
+			input: `---
+vars:
+  service: billing
+  port: 8080
+---
+Content`,
+			expectedFM: Frontmatter{
+				Vars: map[string]any{"service": "billing", "port": 8080},
+			},
+			expectedContent: "Content",
+			shouldError:     false,
+		},
+		{
+			name: "frontmatter with xml tags",
+			input: `---
+xml_tags:
+  document: file
+  content: body
+---
+Content`,
+			expectedFM: Frontmatter{
+				XMLTags: &XMLTags{Document: "file", Content: "body"},
 			},
 			expectedContent: "Content",
 			shouldError:     false,
@@ -85,7 +145,7 @@ Content`,
 				t.Errorf("unexpected error: %v", err)
 			}
 
-			if fm.Extends != tt.expectedFM.Extends {
+			if !slices.Equal(fm.Extends, tt.expectedFM.Extends) {
 				t.Errorf("expected extends %q, got %q", tt.expectedFM.Extends, fm.Extends)
 			}
 
@@ -94,11 +154,23 @@ Content`,
 			}
 
 			for i, include := range tt.expectedFM.Includes {
-				if i >= len(fm.Includes) || fm.Includes[i] != include {
-					t.Errorf("expected include %d to be %q, got %q", i, include, fm.Includes[i])
+				if i >= len(fm.Includes) || fm.Includes[i].Path != include.Path {
+					t.Errorf("expected include %d to be %q, got %q", i, include.Path, fm.Includes[i].Path)
 				}
 			}
 
+			if fm.MaxTokens != tt.expectedFM.MaxTokens {
+				t.Errorf("expected max_tokens %d, got %d", tt.expectedFM.MaxTokens, fm.MaxTokens)
+			}
+
+			if fmt.Sprint(fm.XMLTags) != fmt.Sprint(tt.expectedFM.XMLTags) {
+				t.Errorf("expected xml_tags %+v, got %+v", tt.expectedFM.XMLTags, fm.XMLTags)
+			}
+
+			if fmt.Sprint(fm.Vars) != fmt.Sprint(tt.expectedFM.Vars) {
+				t.Errorf("expected vars %v, got %v", tt.expectedFM.Vars, fm.Vars)
+			}
+
 			if strings.TrimSpace(content) != strings.TrimSpace(tt.expectedContent) {
 				t.Errorf("expected content %q, got %q", tt.expectedContent, content)
 			}
@@ -196,6 +268,271 @@ Main content`,
 			expected: "# Base\nBase content\n\n# Include 1\nInclude content\n\n# Main\nMain content",
 			hasError: false,
 		},
+		{
+			name: "glob includes",
+			files: map[string]string{
+				"rules/b.md":       "# Rule B",
+				"rules/a.md":       "# Rule A",
+				"rules/draft-c.md": "# Draft C",
+				"main.md": `---
+includes:
+  - rules/*.md
+  - "!rules/draft-*.md"
+---
+# Main`,
+			},
+			target:   "main.md",
+			expected: "# Rule A\n\n# Rule B\n\n# Main",
+			hasError: false,
+		},
+		{
+			name: "section include",
+			files: map[string]string{
+				"guide.md": "# Guide\n\n## Setup\n\nSetup text\n\n## Testing\n\nTesting text\n\n## Release\n\nRelease text",
+				"main.md": `---
+includes:
+  - guide.md#testing
+---
+# Main`,
+			},
+			target:   "main.md",
+			expected: "## Testing\n\nTesting text\n\n# Main",
+			hasError: false,
+		},
+		{
+			name: "missing section",
+			files: map[string]string{
+				"guide.md": "# Guide",
+				"main.md": `---
+includes:
+  - guide.md#testing
+---
+# Main`,
+			},
+			target:   "main.md",
+			expected: "",
+			hasError: true,
+		},
+		{
+			name: "source snippets",
+			files: map[string]string{
+				"main.go": "package main\n\nimport \"fmt\"\n\n// fusectx:start greet\nfunc greet() {\n\tfmt.Println(\"hi\")\n}\n// fusectx:end greet\n",
+				"main.md": `---
+includes:
+  - main.go:1-3
+  - main.go:greet
+---
+# Main`,
+			},
+			target:   "main.md",
+			expected: "```go\npackage main\n\nimport \"fmt\"\n```\n\n```go\nfunc greet() {\n\tfmt.Println(\"hi\")\n}\n```\n\n# Main",
+			hasError: false,
+		},
+		{
+			name: "vars override along extends chain",
+			files: map[string]string{
+				"base.md": `---
+vars:
+  service: base
+  port: 80
+---
+# {{ .service }} listens on {{ .port }}`,
+				"common.md": `---
+vars:
+  port: 8080
+  repo: example.com/common
+---
+Repo: {{ .repo }}`,
+				"child.md": `---
+extends: base.md
+includes:
+  - common.md
+vars:
+  service: billing
+---
+Service {{ .service }}`,
+			},
+			target:   "child.md",
+			expected: "# billing listens on 8080\n\nRepo: example.com/common\n\nService billing",
+			hasError: false,
+		},
+		{
+			name: "undefined variable",
+			files: map[string]string{
+				"main.md": "---\nvars:\n  team: core\n---\nService {{ .service }}",
+			},
+			target:   "main.md",
+			expected: "",
+			hasError: true,
+		},
+		{
+			name: "undefined variable without vars",
+			files: map[string]string{
+				"main.md": "Service {{ .service }}",
+			},
+			target:   "main.md",
+			expected: "",
+			hasError: true,
+		},
+		{
+			name: "verbatim file keeps literal braces",
+			files: map[string]string{
+				"ci.md": "---\nverbatim: true\n---\nDeploy `${{ github.sha }}` with {{ .Values.image }}",
+				"main.md": `---
+includes:
+  - ci.md
+vars:
+  team: core
+---
+# {{ .team }}`,
+			},
+			target:   "main.md",
+			expected: "Deploy `${{ github.sha }}` with {{ .Values.image }}\n\n# core",
+			hasError: false,
+		},
+		{
+			name: "verbatim section include",
+			files: map[string]string{
+				"ci.md": "---\nverbatim: true\n---\n## Deploy\nRun `${{ github.sha }}`\n## Other\nSkipped",
+				"main.md": `---
+includes:
+  - ci.md#deploy
+---
+# Main`,
+			},
+			target:   "main.md",
+			expected: "## Deploy\nRun `${{ github.sha }}`\n\n# Main",
+			hasError: false,
+		},
+		{
+			name: "snippets are not templates",
+			files: map[string]string{
+				"tmpl.go": "var t = \"{{ .Name }}\"",
+				"main.md": `---
+includes:
+  - tmpl.go:1
+vars:
+  Name: ignored
+---
+# Main`,
+			},
+			target:   "main.md",
+			expected: "```go\nvar t = \"{{ .Name }}\"\n```\n\n# Main",
+			hasError: false,
+		},
+		{
+			name: "block overrides across extends chain",
+			files: map[string]string{
+				"base.md": `# Base
+<!-- block:style -->
+Use tabs.
+<!-- endblock -->
+<!-- block:review -->
+Review everything.
+<!-- endblock -->`,
+				"team.md": `---
+extends: base.md
+---
+<!-- block:style append -->
+Wrap at 100 columns.
+<!-- endblock -->
+# Team`,
+				"leaf.md": `---
+extends: team.md
+---
+<!-- block:review delete -->
+<!-- block:style -->
+Use spaces.
+<!-- endblock -->
+# Leaf`,
+			},
+			target:   "leaf.md",
+			expected: "# Base\nUse spaces.\n\n# Team\n\n# Leaf",
+			hasError: false,
+		},
+		{
+			name: "block override of undefined block",
+			files: map[string]string{
+				"base.md": "# Base",
+				"child.md": `---
+extends: base.md
+---
+<!-- block:style append -->
+Text
+<!-- endblock -->`,
+			},
+			target:   "child.md",
+			expected: "",
+			hasError: true,
+		},
+		{
+			name: "diamond dependencies are emitted once",
+			files: map[string]string{
+				"common.md": "# Common",
+				"b.md": `---
+extends: common.md
+---
+# B`,
+				"c.md": `---
+extends: common.md
+---
+# C`,
+				"a.md": `---
+includes:
+  - b.md
+  - c.md
+  - common.md
+---
+# A`,
+			},
+			target:   "a.md",
+			expected: "# Common\n\n# B\n\n# C\n\n# A",
+			hasError: false,
+		},
+		{
+			name: "block overrides of an already emitted parent stay in place",
+			files: map[string]string{
+				"common.md": "<!-- block:style -->\nUse tabs.\n<!-- endblock -->",
+				"b.md": `---
+extends: common.md
+---
+# B`,
+				"c.md": `---
+extends: common.md
+---
+<!-- block:style append -->
+Wrap at 100.
+<!-- endblock -->
+# C`,
+				"a.md": `---
+includes:
+  - b.md
+  - c.md
+---
+# A`,
+			},
+			target:   "a.md",
+			expected: "Use tabs.\n\n# B\n\nWrap at 100.\n# C\n\n# A",
+			hasError: false,
+		},
+		{
+			name: "duplicate sections and snippets are emitted once",
+			files: map[string]string{
+				"guide.md": "# Guide\n\n## Testing\n\nTest it.",
+				"main.go":  "package main",
+				"a.md": `---
+includes:
+  - guide.md#testing
+  - guide.md#testing
+  - main.go:1
+  - main.go:1
+---
+# A`,
+			},
+			target:   "a.md",
+			expected: "## Testing\n\nTest it.\n\n```go\npackage main\n```\n\n# A",
+			hasError: false,
+		},
 		{
 			name: "circular dependency",
 			files: map[string]string{
@@ -236,7 +573,11 @@ Content`,
 
 			for filename, content := range tt.files {
 				filePath := filepath.Join(testDir, filename)
-				err := os.WriteFile(filePath, []byte(content), 0644)
+				err := os.MkdirAll(filepath.Dir(filePath), 0755)
+				if err != nil {
+					t.Fatalf("failed to create dir for %s: %v", filename, err)
+				}
+				err = os.WriteFile(filePath, []byte(content), 0644)
 				if err != nil {
 					t.Fatalf("failed to write test file %s: %v", filename, err)
 				}
@@ -352,4 +693,322 @@ Leaf content`,
 			t.Errorf("expected file %s to be in chain, but it wasn't found", expected)
 		}
 	}
-}
\ No newline at end of file
+}
+
+func TestResolveWithOptions(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "fusectx-options-test")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	files := map[string]string{
+		"common.md": `---
+vars:
+  team: platform
+---
+# Common`,
+		"b.md": `---
+extends: common.md
+---
+# B`,
+		"c.md": `---
+extends: common.md
+---
+# C for {{ .team }}`,
+		"a.md": `---
+includes:
+  - b.md
+  - c.md
+---
+# A`,
+	}
+
+	for filename, content := range files {
+		err := os.WriteFile(filepath.Join(tmpDir, filename), []byte(content), 0644)
+		if err != nil {
+			t.Fatalf("failed to write test file %s: %v", filename, err)
+		}
+	}
+
+	tests := []struct {
+		name     string
+		opts     Options
+		expected string
+		hasError bool
+	}{
+		{
+			name:     "duplicates removed by default",
+			opts:     Options{},
+			expected: "# Common\n\n# B\n\n# C for platform\n\n# A",
+		},
+		{
+			name:     "duplicates allowed",
+			opts:     Options{AllowDuplicates: true},
+			expected: "# Common\n\n# B\n\n# Common\n\n# C for platform\n\n# A",
+		},
+		{
+			name:     "vars override frontmatter",
+			opts:     Options{Vars: map[string]string{"team": "billing"}},
+			expected: "# Common\n\n# B\n\n# C for billing\n\n# A",
+		},
+		{
+			name:     "within max depth",
+			opts:     Options{MaxDepth: 2},
+			expected: "# Common\n\n# B\n\n# C for platform\n\n# A",
+		},
+		{
+			name:     "max depth exceeded",
+			opts:     Options{MaxDepth: 1},
+			hasError: true,
+		},
+		{
+			name: "transforms applied in order",
+			opts: Options{Transforms: []Transform{
+				func(path, content string) (string, error) {
+					return strings.ToUpper(content), nil
+				},
+				func(path, content string) (string, error) {
+					return content + " (" + filepath.Base(path) + ")", nil
+				},
+			}},
+			expected: "# COMMON (common.md)\n\n# B (b.md)\n\n# C FOR PLATFORM (c.md)\n\n# A (a.md)",
+		},
+		{
+			name: "transform error",
+			opts: Options{Transforms: []Transform{
+				func(path, content string) (string, error) {
+					return "", fmt.Errorf("rejected")
+				},
+			}},
+			hasError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := ResolveWithOptions(filepath.Join(tmpDir, "a.md"), tt.opts)
+			if tt.hasError {
+				if err == nil {
+					t.Error("expected error but got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if result.Content != tt.expected {
+				t.Errorf("expected:\n%s\n\ngot:\n%s", tt.expected, result.Content)
+			}
+		})
+	}
+
+	chain, err := GetDependencyChain(filepath.Join(tmpDir, "a.md"), nil)
+	if err != nil {
+		t.Fatalf("unexpected error getting dependency chain: %v", err)
+	}
+
+	var names []string
+	for _, p := range chain {
+		names = append(names, filepath.Base(p))
+	}
+	if strings.Join(names, ",") != "a.md,common.md,b.md,c.md" {
+		t.Errorf("expected de-duplicated chain, got %v", names)
+	}
+}
+
+func TestResolveFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"base.md": {Data: []byte("# Base\n<!-- block:rules -->\nBase rules\n<!-- endblock -->")},
+		"main.md": {Data: []byte(`---
+extends: base.md
+includes:
+  - docs/*.md#usage
+  - src/main.go:handler
+---
+<!-- block:rules -->
+Main rules
+<!-- endblock -->
+# Main`)},
+		"docs/guide.md": {Data: []byte("# Guide\n\n## Usage\n\nRun it.")},
+		"src/main.go":   {Data: []byte("package main\n\n// fusectx:start handler\nfunc handler() {}\n// fusectx:end handler")},
+	}
+
+	tests := []struct {
+		name     string
+		file     string
+		expected string
+		hasError bool
+	}{
+		{
+			name:     "extends, blocks, sections and snippets",
+			file:     "main.md",
+			expected: "# Base\nMain rules\n\n## Usage\n\nRun it.\n\n```go\nfunc handler() {}\n```\n\n# Main",
+		},
+		{
+			name:     "rooted path",
+			file:     "/docs/guide.md",
+			expected: "# Guide\n\n## Usage\n\nRun it.",
+		},
+		{
+			name:     "missing file",
+			file:     "missing.md",
+			hasError: true,
+		},
+		{
+			name:     "outside the filesystem",
+			file:     "../main.md",
+			hasError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := ResolveWithOptions(tt.file, Options{FS: fsys})
+			if tt.hasError {
+				if err == nil {
+					t.Error("expected error but got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if result.Content != tt.expected {
+				t.Errorf("expected:\n%s\n\ngot:\n%s", tt.expected, result.Content)
+			}
+		})
+	}
+
+	chain, err := GetDependencyChainWithOptions("main.md", Options{FS: fsys})
+	if err != nil {
+		t.Fatalf("unexpected error getting dependency chain: %v", err)
+	}
+	if strings.Join(chain, ",") != "base.md,main.md,docs/guide.md#usage,src/main.go:handler" {
+		t.Errorf("unexpected chain %v", chain)
+	}
+}
+
+func TestResolveMultipleParents(t *testing.T) {
+	fsys := fstest.MapFS{
+		"base.md": {Data: []byte("# Base\n<!-- block:rules -->\nBase rules\n<!-- endblock -->")},
+		"go.md": {Data: []byte(`---
+extends: base.md
+includes:
+  - style.md
+vars:
+  owner: go
+---
+<!-- block:rules append -->
+Go rules
+<!-- endblock -->
+# Go`)},
+		"policy.md": {Data: []byte(`---
+extends: base.md
+vars:
+  owner: policy
+---
+<!-- block:rules append -->
+Policy rules
+<!-- endblock -->
+# Policy`)},
+		"style.md": {Data: []byte("# Style")},
+		"main.md": {Data: []byte(`---
+extends:
+  - go.md
+  - policy.md
+---
+# Main by {{ .owner }}`)},
+		"reversed.md": {Data: []byte(`---
+extends: [policy.md, go.md]
+---
+# Main by {{ .owner }}`)},
+		"inconsistent.md": {Data: []byte(`---
+extends: [go.md, base.md]
+---
+# Inconsistent`)},
+		"loop.md": {Data: []byte(`---
+extends: [base.md, loop.md]
+---
+# Loop`)},
+	}
+
+	tests := []struct {
+		name     string
+		file     string
+		expected string
+		chain    string
+		hasError bool
+	}{
+		{
+			name:     "diamond",
+			file:     "main.md",
+			expected: "# Base\nBase rules\nGo rules\nPolicy rules\n\n# Style\n\n# Go\n\n# Policy\n\n# Main by policy",
+			chain:    "base.md,go.md,style.md,policy.md,main.md",
+		},
+		{
+			name:     "later parents override earlier ones",
+			file:     "reversed.md",
+			expected: "# Base\nBase rules\nPolicy rules\nGo rules\n\n# Policy\n\n# Style\n\n# Go\n\n# Main by go",
+			chain:    "base.md,policy.md,go.md,style.md,reversed.md",
+		},
+		{
+			name:     "inconsistent hierarchy",
+			file:     "inconsistent.md",
+			hasError: true,
+		},
+		{
+			name:     "cycle",
+			file:     "loop.md",
+			hasError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := ResolveWithOptions(tt.file, Options{FS: fsys})
+			_, chainErr := GetDependencyChainWithOptions(tt.file, Options{FS: fsys})
+			if tt.hasError {
+				if err == nil || chainErr == nil {
+					t.Error("expected error but got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if result.Content != tt.expected {
+				t.Errorf("expected:\n%s\n\ngot:\n%s", tt.expected, result.Content)
+			}
+
+			chain, err := GetDependencyChainWithOptions(tt.file, Options{FS: fsys})
+			if err != nil {
+				t.Fatalf("unexpected error getting dependency chain: %v", err)
+			}
+			if strings.Join(chain, ",") != tt.chain {
+				t.Errorf("expected chain %s, got %v", tt.chain, chain)
+			}
+		})
+	}
+
+	result, err := ResolveWithOptions("main.md", Options{FS: fsys})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var depths []string
+	for _, seg := range result.Document.Segments {
+		depths = append(depths, fmt.Sprintf("%s:%s:%d", seg.Path, seg.Relation, seg.Depth))
+	}
+	expected := "base.md:extends:2,style.md:include:2,go.md:extends:1,policy.md:extends:1,main.md:self:0"
+	if strings.Join(depths, ",") != expected {
+		t.Errorf("expected segments %s, got %s", expected, strings.Join(depths, ","))
+	}
+
+	_, err = ResolveWithOptions("inconsistent.md", Options{FS: fsys})
+	if err == nil || !strings.Contains(err.Error(), "inconsistent extends hierarchy in inconsistent.md: cannot order base.md, go.md") {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
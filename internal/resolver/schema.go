@@ -0,0 +1,95 @@
+package resolver
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+func unknownKeys(node *yaml.Node, t reflect.Type) []*FrontmatterError {
+	switch t.Kind() {
+	case reflect.Pointer:
+		return unknownKeys(node, t.Elem())
+	case reflect.Slice:
+		if node.Kind != yaml.SequenceNode {
+			return nil
+		}
+		var unknown []*FrontmatterError
+		for _, item := range node.Content {
+			unknown = append(unknown, unknownKeys(item, t.Elem())...)
+		}
+		return unknown
+	case reflect.Struct:
+		if node.Kind != yaml.MappingNode {
+			return nil
+		}
+		fields := yamlFields(t)
+		var unknown []*FrontmatterError
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i], node.Content[i+1]
+			fieldType, ok := fields[key.Value]
+			if !ok {
+				unknown = append(unknown, &FrontmatterError{
+					Line:    key.Line,
+					Column:  key.Column,
+					Message: unknownKeyMessage(key.Value, fields),
+				})
+				continue
+			}
+			unknown = append(unknown, unknownKeys(value, fieldType)...)
+		}
+		return unknown
+	default:
+		return nil
+	}
+}
+
+func yamlFields(t reflect.Type) map[string]reflect.Type {
+	fields := make(map[string]reflect.Type)
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if name, _, _ := strings.Cut(field.Tag.Get("yaml"), ","); name != "" && name != "-" {
+			fields[name] = field.Type
+		}
+	}
+	return fields
+}
+
+func unknownKeyMessage(key string, fields map[string]reflect.Type) string {
+	best, bestDistance := "", len(key)
+	for name := range fields {
+		distance := editDistance(key, name)
+		if distance < bestDistance || (distance == bestDistance && name < best) {
+			best, bestDistance = name, distance
+		}
+	}
+
+	if best != "" && bestDistance <= max(2, len(key)/3) {
+		return fmt.Sprintf("unknown key %q, did you mean %q?", key, best)
+	}
+	return fmt.Sprintf("unknown key %q", key)
+}
+
+func editDistance(a, b string) int {
+	previous := make([]int, len(b)+1)
+	current := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+
+	return previous[len(b)]
+}
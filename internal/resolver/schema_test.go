@@ -0,0 +1,128 @@
+package resolver
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestUnknownKeys(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected []string
+	}{
+		{
+			name:  "known keys",
+			input: "---\nextends: base.md\nincludes:\n  - a.md\n  - path: b.md\n    priority: 1\nvars:\n  anything: 1\nxml_tags:\n  document: file\n---\nContent",
+		},
+		{
+			name:     "typo with suggestion",
+			input:    "---\ninclude:\n  - a.md\n---\nContent",
+			expected: []string{`2:1: unknown key "include", did you mean "includes"?`},
+		},
+		{
+			name:     "unknown key without suggestion",
+			input:    "---\nextends: base.md\ndescription: Base\n---\nContent",
+			expected: []string{`3:1: unknown key "description"`},
+		},
+		{
+			name:  "nested keys",
+			input: "---\nincludes:\n  - path: a.md\n    priorty: 1\nxml_tags:\n  documnet: file\n---\nContent",
+			expected: []string{
+				`4:5: unknown key "priorty", did you mean "priority"?`,
+				`6:3: unknown key "documnet", did you mean "document"?`,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fm, _, err := parseFrontmatter(strings.NewReader(tt.input), false)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			var actual []string
+			for _, unknown := range fm.unknownKeys {
+				actual = append(actual, fmt.Sprintf("%d:%d: %s", unknown.Line, unknown.Column, unknown.Message))
+			}
+			if !slices.Equal(actual, tt.expected) {
+				t.Errorf("expected %q, got %q", tt.expected, actual)
+			}
+
+			_, _, err = ParseFrontmatter(strings.NewReader(tt.input))
+			var frontmatterErr *FrontmatterError
+			if len(tt.expected) > 0 && !errors.As(err, &frontmatterErr) {
+				t.Errorf("expected strict parsing to fail, got %v", err)
+			}
+			if len(tt.expected) == 0 && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestFrontmatterSchema(t *testing.T) {
+	data, err := os.ReadFile("../../schema/frontmatter.schema.json")
+	if err != nil {
+		t.Fatalf("failed to read schema: %v", err)
+	}
+
+	type object struct {
+		Properties map[string]json.RawMessage `json:"properties"`
+	}
+	var schema struct {
+		object
+		Defs map[string]json.RawMessage `json:"$defs"`
+	}
+	if err := json.Unmarshal(data, &schema); err != nil {
+		t.Fatalf("invalid schema: %v", err)
+	}
+
+	var includes struct {
+		Items struct {
+			OneOf []object `json:"oneOf"`
+		} `json:"items"`
+	}
+	if err := json.Unmarshal(schema.Properties["includes"], &includes); err != nil {
+		t.Fatalf("invalid includes schema: %v", err)
+	}
+	var xmlTags object
+	if err := json.Unmarshal(schema.Properties["xml_tags"], &xmlTags); err != nil {
+		t.Fatalf("invalid xml_tags schema: %v", err)
+	}
+	var condition struct {
+		OneOf []object `json:"oneOf"`
+	}
+	if err := json.Unmarshal(schema.Defs["condition"], &condition); err != nil {
+		t.Fatalf("invalid condition schema: %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		properties map[string]json.RawMessage
+		typ        reflect.Type
+	}{
+		{name: "frontmatter", properties: schema.Properties, typ: reflect.TypeFor[Frontmatter]()},
+		{name: "includes", properties: includes.Items.OneOf[len(includes.Items.OneOf)-1].Properties, typ: reflect.TypeFor[Include]()},
+		{name: "xml_tags", properties: xmlTags.Properties, typ: reflect.TypeFor[XMLTags]()},
+		{name: "when", properties: condition.OneOf[len(condition.OneOf)-1].Properties, typ: reflect.TypeFor[Condition]()},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expected := slices.Sorted(maps.Keys(yamlFields(tt.typ)))
+			actual := slices.Sorted(maps.Keys(tt.properties))
+			if !slices.Equal(actual, expected) {
+				t.Errorf("expected schema properties %v, got %v", expected, actual)
+			}
+		})
+	}
+}
@@ -0,0 +1,114 @@
+package resolver
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+type heading struct {
+	line  int
+	level int
+	slug  string
+}
+
+func extractSection(content, anchor string) (string, error) {
+	lines := strings.Split(content, "\n")
+	headings := findHeadings(lines)
+
+	for i, h := range headings {
+		if h.slug != anchor {
+			continue
+		}
+
+		end := len(lines)
+		for _, next := range headings[i+1:] {
+			if next.level <= h.level {
+				end = next.line
+				break
+			}
+		}
+
+		return strings.TrimSpace(strings.Join(lines[h.line:end], "\n")), nil
+	}
+
+	return "", fmt.Errorf("section #%s not found", anchor)
+}
+
+func findHeadings(lines []string) []heading {
+	var headings []heading
+	var fence string
+	slugCounts := make(map[string]int)
+
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+
+		if fence != "" {
+			if strings.HasPrefix(trimmed, fence) {
+				fence = ""
+			}
+			continue
+		}
+		if strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~") {
+			fence = trimmed[:3]
+			continue
+		}
+
+		level, text, ok := parseHeading(line)
+		if !ok {
+			continue
+		}
+
+		slug := slugify(text)
+		if n := slugCounts[slug]; n > 0 {
+			slugCounts[slug]++
+			slug = fmt.Sprintf("%s-%d", slug, n)
+		} else {
+			slugCounts[slug] = 1
+		}
+
+		headings = append(headings, heading{line: i, level: level, slug: slug})
+	}
+
+	return headings
+}
+
+func parseHeading(line string) (int, string, bool) {
+	if strings.HasPrefix(line, "    ") {
+		return 0, "", false
+	}
+
+	trimmed := strings.TrimLeft(line, " ")
+	level := 0
+	for level < len(trimmed) && trimmed[level] == '#' {
+		level++
+	}
+	if level == 0 || level > 6 {
+		return 0, "", false
+	}
+
+	rest := trimmed[level:]
+	if rest != "" && rest[0] != ' ' && rest[0] != '\t' {
+		return 0, "", false
+	}
+
+	text := strings.TrimSpace(rest)
+	if closing := strings.TrimRight(text, "#"); closing == "" || strings.HasSuffix(closing, " ") {
+		text = strings.TrimSpace(closing)
+	}
+
+	return level, text, true
+}
+
+func slugify(text string) string {
+	var slug strings.Builder
+	for _, r := range strings.ToLower(text) {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r) || r == '-' || r == '_':
+			slug.WriteRune(r)
+		case r == ' ':
+			slug.WriteRune('-')
+		}
+	}
+	return slug.String()
+}
@@ -0,0 +1,116 @@
+package resolver
+
+import (
+	"testing"
+)
+
+func TestExtractSection(t *testing.T) {
+	content := `# Guide
+
+Intro text.
+
+## Getting Started
+
+Install it.
+
+## Testing
+
+Run the tests.
+
+### Unit Tests
+
+Use go test.
+
+` + "```bash" + `
+# not a heading
+` + "```" + `
+
+## Release Process ##
+
+Tag a version.
+
+## Testing
+
+Second testing section.`
+
+	tests := []struct {
+		name     string
+		anchor   string
+		expected string
+		hasError bool
+	}{
+		{
+			name:     "section ends at next heading of same level",
+			anchor:   "getting-started",
+			expected: "## Getting Started\n\nInstall it.",
+		},
+		{
+			name:     "section includes nested headings and code blocks",
+			anchor:   "testing",
+			expected: "## Testing\n\nRun the tests.\n\n### Unit Tests\n\nUse go test.\n\n```bash\n# not a heading\n```",
+		},
+		{
+			name:     "nested section",
+			anchor:   "unit-tests",
+			expected: "### Unit Tests\n\nUse go test.\n\n```bash\n# not a heading\n```",
+		},
+		{
+			name:     "closing hashes are ignored",
+			anchor:   "release-process",
+			expected: "## Release Process ##\n\nTag a version.",
+		},
+		{
+			name:     "duplicate headings get numbered slugs",
+			anchor:   "testing-1",
+			expected: "## Testing\n\nSecond testing section.",
+		},
+		{
+			name:     "top level section runs to the end",
+			anchor:   "guide",
+			expected: content,
+		},
+		{
+			name:     "missing anchor",
+			anchor:   "deployment",
+			hasError: true,
+		},
+		{
+			name:     "headings inside code blocks are not anchors",
+			anchor:   "not-a-heading",
+			hasError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			section, err := extractSection(content, tt.anchor)
+
+			if tt.hasError && err == nil {
+				t.Error("expected error but got none")
+			}
+			if !tt.hasError && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+
+			if !tt.hasError && section != tt.expected {
+				t.Errorf("expected:\n%s\n\ngot:\n%s", tt.expected, section)
+			}
+		})
+	}
+}
+
+func TestSlugify(t *testing.T) {
+	tests := map[string]string{
+		"Testing":              "testing",
+		"Getting Started":      "getting-started",
+		"What's new in v1.2?":  "whats-new-in-v12",
+		"snake_case and-dash":  "snake_case-and-dash",
+		"`code` **bold** text": "code-bold-text",
+	}
+
+	for input, expected := range tests {
+		if actual := slugify(input); actual != expected {
+			t.Errorf("slugify(%q): expected %q, got %q", input, expected, actual)
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package resolver
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	relationSelf    = "self"
+	relationExtends = "extends"
+	relationInclude = "include"
+)
+
+const segmentSeparator = "\n\n"
+
+type segment struct {
+	path     string
+	relation string
+	depth    int
+	included bool
+	priority *int
+	content  string
+	line     int
+	verbatim bool
+}
+
+func nest(segments []segment, relation string) []segment {
+	nested := make([]segment, len(segments))
+	for i, seg := range segments {
+		if seg.depth == 0 {
+			seg.relation = relation
+		}
+		seg.depth++
+		nested[i] = seg
+	}
+	return nested
+}
+
+func joinSegments(segments []segment) (string, error) {
+	var parts []string
+	for _, seg := range segments {
+		content, err := stripBlocks(seg.content)
+		if err != nil {
+			return "", fmt.Errorf("error parsing blocks in %s: %w", seg.path, err)
+		}
+		if content = strings.TrimSpace(content); content != "" {
+			parts = append(parts, content)
+		}
+	}
+	return strings.Join(parts, segmentSeparator), nil
+}
+
+func allVerbatim(segments []segment) bool {
+	for _, seg := range segments {
+		if !seg.verbatim {
+			return false
+		}
+	}
+	return true
+}
+
+type renderedSegment struct {
+	segment
+	text string
+}
+
+func renderSegments(segments []segment, vars map[string]any, transforms []Transform) ([]renderedSegment, error) {
+	var rendered []renderedSegment
+	for _, seg := range segments {
+		text := seg.content
+		if !seg.verbatim {
+			var err error
+			text, err = renderTemplate(seg.path, text, seg.line, vars)
+			if err != nil {
+				return nil, fmt.Errorf("error rendering %s: %w", seg.path, err)
+			}
+		}
+		text, err := stripBlocks(text)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing blocks in %s: %w", seg.path, err)
+		}
+		text = strings.TrimSpace(text)
+		if text == "" {
+			continue
+		}
+
+		for _, transform := range transforms {
+			text, err = transform(seg.path, text)
+			if err != nil {
+				return nil, fmt.Errorf("error transforming %s: %w", seg.path, err)
+			}
+		}
+		if text = strings.TrimSpace(text); text != "" {
+			rendered = append(rendered, renderedSegment{segment: seg, text: text})
+		}
+	}
+	return rendered, nil
+}
+
+func render(segments []segment, vars map[string]any, transforms []Transform) (string, error) {
+	rendered, err := renderSegments(segments, vars, transforms)
+	if err != nil {
+		return "", err
+	}
+	return joinRendered(rendered), nil
+}
+
+func joinRendered(rendered []renderedSegment) string {
+	parts := make([]string, len(rendered))
+	for i, seg := range rendered {
+		parts[i] = seg.text
+	}
+	return strings.Join(parts, segmentSeparator)
+}
@@ -0,0 +1,140 @@
+package resolver
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+var regionMarkerPattern = regexp.MustCompile(`fusectx:(start|end)\s+([A-Za-z0-9_.-]+)`)
+
+var languages = map[string]string{
+	".go":    "go",
+	".ts":    "typescript",
+	".tsx":   "tsx",
+	".js":    "javascript",
+	".jsx":   "jsx",
+	".mjs":   "javascript",
+	".py":    "python",
+	".rb":    "ruby",
+	".rs":    "rust",
+	".java":  "java",
+	".kt":    "kotlin",
+	".scala": "scala",
+	".swift": "swift",
+	".c":     "c",
+	".h":     "c",
+	".cc":    "cpp",
+	".cpp":   "cpp",
+	".hpp":   "cpp",
+	".cs":    "csharp",
+	".php":   "php",
+	".lua":   "lua",
+	".sh":    "bash",
+	".bash":  "bash",
+	".zsh":   "zsh",
+	".ps1":   "powershell",
+	".sql":   "sql",
+	".proto": "protobuf",
+	".yaml":  "yaml",
+	".yml":   "yaml",
+	".json":  "json",
+	".toml":  "toml",
+	".xml":   "xml",
+	".html":  "html",
+	".css":   "css",
+	".scss":  "scss",
+	".tf":    "hcl",
+	".txt":   "text",
+}
+
+var languagesByName = map[string]string{
+	"Dockerfile": "dockerfile",
+	"Makefile":   "makefile",
+}
+
+func (r *resolution) readSnippet(include includeRef) (string, error) {
+	data, err := r.files.readFile(include.Path)
+	if err != nil {
+		return "", openError(include.Path, include.from, err)
+	}
+
+	lines := strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+
+	var snippet []string
+	if include.Region != "" {
+		snippet, err = extractRegion(lines, include.Region)
+	} else {
+		snippet, err = extractLines(lines, include.StartLine, include.EndLine)
+	}
+	if err != nil {
+		return "", fmt.Errorf("error reading %s: %w", include.Path, err)
+	}
+
+	return fenceSnippet(strings.Join(snippet, "\n"), include.Path), nil
+}
+
+func extractLines(lines []string, start, end int) ([]string, error) {
+	if start > len(lines) {
+		return nil, fmt.Errorf("line range %d-%d is out of bounds (file has %d lines)", start, end, len(lines))
+	}
+	if end > len(lines) {
+		end = len(lines)
+	}
+	return lines[start-1 : end], nil
+}
+
+func extractRegion(lines []string, name string) ([]string, error) {
+	start := -1
+	for i, line := range lines {
+		m := regionMarkerPattern.FindStringSubmatch(line)
+		if m == nil || m[2] != name {
+			continue
+		}
+
+		if m[1] == "start" {
+			if start >= 0 {
+				return nil, fmt.Errorf("region %q is started twice (line %d)", name, i+1)
+			}
+			start = i
+			continue
+		}
+
+		if start < 0 {
+			return nil, fmt.Errorf("region %q ends before it starts (line %d)", name, i+1)
+		}
+
+		var region []string
+		for _, regionLine := range lines[start+1 : i] {
+			if !regionMarkerPattern.MatchString(regionLine) {
+				region = append(region, regionLine)
+			}
+		}
+		return region, nil
+	}
+
+	if start >= 0 {
+		return nil, fmt.Errorf("region %q is never closed", name)
+	}
+	return nil, fmt.Errorf("region %q not found", name)
+}
+
+func fenceSnippet(snippet, path string) string {
+	ext := strings.ToLower(filepath.Ext(path))
+	if ext == ".md" || ext == ".markdown" {
+		return snippet
+	}
+
+	language, ok := languagesByName[filepath.Base(path)]
+	if !ok {
+		language = languages[ext]
+	}
+
+	fence := "```"
+	for strings.Contains(snippet, fence) {
+		fence += "`"
+	}
+
+	return fence + language + "\n" + snippet + "\n" + fence
+}
@@ -0,0 +1,148 @@
+package resolver
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestExtractRegion(t *testing.T) {
+	source := `package main
+
+// fusectx:start handler
+func handler() {
+	// fusectx:start body
+	doWork()
+	// fusectx:end body
+}
+// fusectx:end handler
+
+# fusectx:start unclosed
+x = 1`
+
+	tests := []struct {
+		name     string
+		region   string
+		expected string
+		hasError bool
+	}{
+		{
+			name:     "nested markers are stripped",
+			region:   "handler",
+			expected: "func handler() {\n\tdoWork()\n}",
+		},
+		{
+			name:     "inner region",
+			region:   "body",
+			expected: "\tdoWork()",
+		},
+		{
+			name:     "unclosed region",
+			region:   "unclosed",
+			hasError: true,
+		},
+		{
+			name:     "missing region",
+			region:   "missing",
+			hasError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			region, err := extractRegion(strings.Split(source, "\n"), tt.region)
+
+			if tt.hasError && err == nil {
+				t.Error("expected error but got none")
+			}
+			if !tt.hasError && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+
+			if !tt.hasError && strings.Join(region, "\n") != tt.expected {
+				t.Errorf("expected:\n%s\n\ngot:\n%s", tt.expected, strings.Join(region, "\n"))
+			}
+		})
+	}
+}
+
+func TestExtractLines(t *testing.T) {
+	lines := []string{"one", "two", "three", "four"}
+
+	tests := []struct {
+		name     string
+		start    int
+		end      int
+		expected string
+		hasError bool
+	}{
+		{name: "range", start: 2, end: 3, expected: "two\nthree"},
+		{name: "single line", start: 4, end: 4, expected: "four"},
+		{name: "end is clamped", start: 3, end: 40, expected: "three\nfour"},
+		{name: "start out of bounds", start: 5, end: 6, hasError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			snippet, err := extractLines(lines, tt.start, tt.end)
+
+			if tt.hasError && err == nil {
+				t.Error("expected error but got none")
+			}
+			if !tt.hasError && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+
+			if !tt.hasError && strings.Join(snippet, "\n") != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, strings.Join(snippet, "\n"))
+			}
+		})
+	}
+}
+
+func TestFenceSnippet(t *testing.T) {
+	tests := []struct {
+		name     string
+		snippet  string
+		path     string
+		expected string
+	}{
+		{
+			name:     "language from extension",
+			snippet:  "x := 1",
+			path:     "main.go",
+			expected: "```go\nx := 1\n```",
+		},
+		{
+			name:     "language from file name",
+			snippet:  "FROM scratch",
+			path:     "build/Dockerfile",
+			expected: "```dockerfile\nFROM scratch\n```",
+		},
+		{
+			name:     "unknown extension",
+			snippet:  "data",
+			path:     "file.unknown",
+			expected: "```\ndata\n```",
+		},
+		{
+			name:     "markdown is not fenced",
+			snippet:  "# Title",
+			path:     "doc.md",
+			expected: "# Title",
+		},
+		{
+			name:     "fence is longer than inner backticks",
+			snippet:  "// ```example```",
+			path:     "doc.ts",
+			expected: "````typescript\n// ```example```\n````",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if actual := fenceSnippet(tt.snippet, tt.path); actual != tt.expected {
+				t.Errorf("expected:\n%s\n\ngot:\n%s", tt.expected, actual)
+			}
+		})
+	}
+}
@@ -0,0 +1,34 @@
+package resolver
+
+import (
+	"fmt"
+	"strings"
+	"text/template"
+)
+
+const templateDelimiter = "{{"
+
+func renderTemplate(name, content string, line int, vars map[string]any) (string, error) {
+	if !strings.Contains(content, templateDelimiter) {
+		return content, nil
+	}
+
+	padding := strings.Repeat("\n", max(line-1, 0))
+	tmpl, err := template.New(name).Option("missingkey=error").Parse(padding + content)
+	if err != nil {
+		return "", fmt.Errorf("error parsing template: %w", err)
+	}
+
+	var result strings.Builder
+	if err := tmpl.Execute(&result, vars); err != nil {
+		return "", fmt.Errorf("error rendering template: %w", err)
+	}
+
+	return strings.TrimPrefix(result.String(), padding), nil
+}
+
+func mergeVars(dst, src map[string]any) {
+	for key, value := range src {
+		dst[key] = value
+	}
+}
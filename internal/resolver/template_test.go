@@ -0,0 +1,105 @@
+package resolver
+
+import (
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestRenderTemplate(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		line     int
+		vars     map[string]any
+		expected string
+		hasError bool
+		errorAt  string
+	}{
+		{
+			name:     "no template actions",
+			content:  "# Plain content",
+			expected: "# Plain content",
+		},
+		{
+			name:     "substitution",
+			content:  "{{ .service }} on port {{ .port }}",
+			vars:     map[string]any{"service": "billing", "port": 8080},
+			expected: "billing on port 8080",
+		},
+		{
+			name:     "escaped delimiters",
+			content:  `Use {{"{{"}} .Name }} in templates`,
+			vars:     map[string]any{"service": "billing"},
+			expected: "Use {{ .Name }} in templates",
+		},
+		{
+			name:     "undefined variable without vars",
+			content:  "Service {{ .service }}",
+			hasError: true,
+		},
+		{
+			name:     "literal braces without vars",
+			content:  "Deploy `${{ github.sha }}`",
+			hasError: true,
+		},
+		{
+			name:     "undefined variable",
+			content:  "{{ .missing }}",
+			vars:     map[string]any{"service": "billing"},
+			hasError: true,
+		},
+		{
+			name:     "invalid template",
+			content:  "{{ .service ",
+			vars:     map[string]any{"service": "billing"},
+			hasError: true,
+		},
+		{
+			name:     "leading lines are preserved",
+			content:  "\n# Title\n{{ .service }}",
+			line:     4,
+			vars:     map[string]any{"service": "billing"},
+			expected: "\n# Title\nbilling",
+		},
+		{
+			name:     "error reports the source line",
+			content:  "# Title\n\nUses {{ .missing }}",
+			line:     5,
+			vars:     map[string]any{"service": "billing"},
+			hasError: true,
+			errorAt:  "test.md:7:",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := renderTemplate("test.md", tt.content, tt.line, tt.vars)
+
+			if tt.hasError && err == nil {
+				t.Error("expected error but got none")
+			}
+			if !tt.hasError && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if tt.errorAt != "" && (err == nil || !strings.Contains(err.Error(), tt.errorAt)) {
+				t.Errorf("expected error at %s, got %v", tt.errorAt, err)
+			}
+
+			if !tt.hasError && result != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestTemplateErrorLines(t *testing.T) {
+	fsys := fstest.MapFS{
+		"main.md": {Data: []byte("---\nvars:\n  team: core\n---\n\n# {{ .team }}\n<!-- block:body -->\nUses {{ .missing }}\n<!-- endblock -->")},
+	}
+
+	_, err := ResolveWithOptions("main.md", Options{FS: fsys})
+	if err == nil || !strings.Contains(err.Error(), "main.md:8:") {
+		t.Errorf("expected an error at main.md:8, got %v", err)
+	}
+}
@@ -0,0 +1,160 @@
+package resolver
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const (
+	ProblemCycle       = "cycle"
+	ProblemMissingFile = "missing_file"
+	ProblemFrontmatter = "frontmatter"
+	ProblemError       = "error"
+)
+
+type ValidationError struct {
+	Errors []error
+}
+
+func (e *ValidationError) Error() string {
+	if len(e.Errors) == 1 {
+		return e.Errors[0].Error()
+	}
+
+	messages := make([]string, len(e.Errors))
+	for i, err := range e.Errors {
+		messages[i] = err.Error()
+	}
+	return fmt.Sprintf("%d problems found:\n%s", len(e.Errors), strings.Join(messages, "\n"))
+}
+
+func (e *ValidationError) Unwrap() []error {
+	return e.Errors
+}
+
+type Problem struct {
+	Kind    string `json:"kind"`
+	Path    string `json:"path,omitempty"`
+	Line    int    `json:"line,omitempty"`
+	Column  int    `json:"column,omitempty"`
+	Message string `json:"message"`
+}
+
+func (e *ValidationError) Problems() []Problem {
+	problems := make([]Problem, len(e.Errors))
+	for i, err := range e.Errors {
+		problems[i] = problemOf(err)
+	}
+	return problems
+}
+
+func problemOf(err error) Problem {
+	var cycleErr *CycleError
+	var missingErr *MissingFileError
+	var frontmatterErr *FrontmatterError
+
+	switch {
+	case errors.As(err, &cycleErr):
+		return Problem{Kind: ProblemCycle, Path: cycleErr.Cycle[0], Message: cycleErr.Error()}
+	case errors.As(err, &missingErr):
+		if missingErr.Referrer == "" {
+			return Problem{Kind: ProblemMissingFile, Path: missingErr.Path, Message: "file does not exist"}
+		}
+		return Problem{
+			Kind:    ProblemMissingFile,
+			Path:    missingErr.Referrer,
+			Line:    missingErr.Line,
+			Message: fmt.Sprintf("file %s does not exist", missingErr.Path),
+		}
+	case errors.As(err, &frontmatterErr):
+		return Problem{
+			Kind:    ProblemFrontmatter,
+			Path:    frontmatterErr.Path,
+			Line:    frontmatterErr.Line,
+			Column:  frontmatterErr.Column,
+			Message: frontmatterErr.Message,
+		}
+	default:
+		return Problem{Kind: ProblemError, Message: err.Error()}
+	}
+}
+
+type validation struct {
+	*resolution
+	checked map[string]bool
+	errors  []error
+}
+
+func ValidateChain(filePath string) error {
+	return ValidateChainWithOptions(filePath, Options{})
+}
+
+func ValidateChainWithOptions(filePath string, opts Options) error {
+	v := &validation{resolution: newResolution(nil, opts), checked: make(map[string]bool)}
+	v.check(filePath, position{})
+
+	if len(v.errors) == 0 {
+		if _, err := ResolveWithOptions(filePath, opts); err != nil {
+			v.errors = append(v.errors, err)
+		}
+	}
+
+	if len(v.errors) > 0 {
+		return &ValidationError{Errors: v.errors}
+	}
+	return nil
+}
+
+func (v *validation) check(filePath string, from position) {
+	absPath, err := v.files.abs(filePath)
+	if err != nil {
+		v.errors = append(v.errors, fmt.Errorf("error resolving absolute path for %s: %w", filePath, err))
+		return
+	}
+
+	leave, err := v.enter(absPath)
+	if err != nil {
+		v.errors = append(v.errors, err)
+		return
+	}
+	defer leave()
+
+	if v.checked[absPath] {
+		return
+	}
+	v.checked[absPath] = true
+
+	frontmatter, _, err := v.parseFile(absPath, from, false)
+	if err != nil {
+		v.errors = append(v.errors, err)
+		return
+	}
+
+	for _, unknown := range frontmatter.unknownKeys {
+		v.errors = append(v.errors, unknown)
+	}
+
+	for _, parent := range v.parents(frontmatter, absPath) {
+		v.check(parent.path, parent.from)
+	}
+
+	includes, err := v.expandIncludes(frontmatter.Includes, v.files.dir(absPath), absPath)
+	if err != nil {
+		v.errors = append(v.errors, fmt.Errorf("error expanding includes in %s: %w", absPath, err))
+	}
+
+	for _, include := range includes {
+		if !include.isSnippet() {
+			v.check(include.Path, include.from)
+			continue
+		}
+
+		file, err := v.files.open(include.Path)
+		if err != nil {
+			v.errors = append(v.errors, openError(include.Path, include.from, err))
+			continue
+		}
+		file.Close()
+	}
+}
@@ -0,0 +1,90 @@
+package resolver
+
+import (
+	"errors"
+	"testing"
+	"testing/fstest"
+)
+
+func TestValidateChainWithOptions(t *testing.T) {
+	fsys := fstest.MapFS{
+		"valid.md":   {Data: []byte("---\nextends: base.md\nincludes:\n  - main.go:1\n---\n# Valid")},
+		"base.md":    {Data: []byte("# Base")},
+		"main.go":    {Data: []byte("package main")},
+		"cycle.md":   {Data: []byte("---\nincludes:\n  - cycle-b.md\n---\n# A")},
+		"cycle-b.md": {Data: []byte("---\nextends: cycle.md\n---\n# B")},
+		"broken.md":  {Data: []byte("---\nextends: base.md\nmax_tokens: [1]\n---\n# Broken")},
+		"all.md": {Data: []byte(`---
+extends: missing.md
+includes:
+  - cycle.md
+  - broken.md
+  - gone.go:1-3
+  - base.md
+unknown: true
+---
+# All`)},
+		"template.md": {Data: []byte("---\nextends: base.md\nvars:\n  team: core\n---\n# {{ .undefined }}")},
+	}
+
+	tests := []struct {
+		file     string
+		problems []Problem
+	}{
+		{file: "valid.md"},
+		{
+			file: "all.md",
+			problems: []Problem{
+				{Kind: ProblemFrontmatter, Path: "all.md", Line: 8, Column: 1, Message: `unknown key "unknown"`},
+				{Kind: ProblemMissingFile, Path: "all.md", Line: 2, Message: "file missing.md does not exist"},
+				{Kind: ProblemCycle, Path: "cycle.md", Message: "circular dependency detected: cycle.md -> cycle-b.md -> cycle.md"},
+				{Kind: ProblemFrontmatter, Path: "broken.md", Line: 3, Column: 13, Message: "cannot unmarshal !!seq into int"},
+				{Kind: ProblemMissingFile, Path: "all.md", Line: 6, Message: "file gone.go does not exist"},
+			},
+		},
+		{
+			file:     "missing.md",
+			problems: []Problem{{Kind: ProblemMissingFile, Path: "missing.md", Message: "file does not exist"}},
+		},
+		{
+			file:     "template.md",
+			problems: []Problem{{Kind: ProblemError}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			err := ValidateChainWithOptions(tt.file, Options{FS: fsys})
+			if len(tt.problems) == 0 {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+
+			var validationErr *ValidationError
+			if !errors.As(err, &validationErr) {
+				t.Fatalf("expected a ValidationError, got %v", err)
+			}
+
+			problems := validationErr.Problems()
+			if len(problems) != len(tt.problems) {
+				t.Fatalf("expected %d problems, got %d: %+v", len(tt.problems), len(problems), problems)
+			}
+			for i, problem := range problems {
+				expected := tt.problems[i]
+				if expected.Kind == ProblemError {
+					expected.Message = problem.Message
+				}
+				if problem != expected {
+					t.Errorf("expected problem %+v, got %+v", expected, problem)
+				}
+			}
+		})
+	}
+
+	var cycleErr *CycleError
+	if err := ValidateChainWithOptions("all.md", Options{FS: fsys}); !errors.As(err, &cycleErr) {
+		t.Errorf("expected the validation error to wrap a CycleError, got %v", err)
+	}
+}
@@ -0,0 +1,40 @@
+package tokenizer
+
+import (
+	"compress/gzip"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+)
+
+//go:generate go run fetch_ranks.go
+
+//go:embed ranks
+var embeddedRanks embed.FS
+
+const DefaultEncoding = "cl100k_base"
+
+func LoadEmbedded(name string) (*BPE, error) {
+	file, err := embeddedRanks.Open("ranks/" + name + ".tiktoken.gz")
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("no %s rank table is embedded in this build; run go generate ./internal/tokenizer or pass a rank file", name)
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	reader, err := gzip.NewReader(file)
+	if err != nil {
+		return nil, fmt.Errorf("error reading embedded %s ranks: %w", name, err)
+	}
+	defer reader.Close()
+
+	bpe, err := LoadBPE(reader)
+	if err != nil {
+		return nil, fmt.Errorf("error reading embedded %s ranks: %w", name, err)
+	}
+	bpe.name = name
+	return bpe, nil
+}
@@ -0,0 +1,70 @@
+//go:build ignore
+
+package main
+
+import (
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+)
+
+var tables = []struct {
+	name string
+	url  string
+	hash string
+}{
+	{
+		name: "cl100k_base",
+		url:  "https://openaipublic.blob.core.windows.net/encodings/cl100k_base.tiktoken",
+		hash: "223921b76ee99bde995b7ff738513eef100fb51d18c93597a113bcffe865b2a7",
+	},
+}
+
+func main() {
+	for _, table := range tables {
+		if err := fetch(table.name, table.url, table.hash); err != nil {
+			fmt.Fprintf(os.Stderr, "failed to fetch %s: %v\n", table.name, err)
+			os.Exit(1)
+		}
+	}
+}
+
+func fetch(name, url, hash string) error {
+	response, err := http.Get(url)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %s", response.Status)
+	}
+
+	data, err := io.ReadAll(response.Body)
+	if err != nil {
+		return err
+	}
+	sum := sha256.Sum256(data)
+	if actual := hex.EncodeToString(sum[:]); actual != hash {
+		return fmt.Errorf("expected sha256 %s, got %s", hash, actual)
+	}
+
+	file, err := os.Create(filepath.Join("ranks", name+".tiktoken.gz"))
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	writer, err := gzip.NewWriterLevel(file, gzip.BestCompression)
+	if err != nil {
+		return err
+	}
+	if _, err := writer.Write(data); err != nil {
+		return err
+	}
+	return writer.Close()
+}
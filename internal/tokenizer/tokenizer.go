@@ -0,0 +1,37 @@
+package tokenizer
+
+import (
+	"fmt"
+	"unicode/utf8"
+)
+
+type Tokenizer interface {
+	Name() string
+	CountTokens(text string) int
+}
+
+const charsPerToken = 4
+
+type Heuristic struct{}
+
+func (Heuristic) Name() string {
+	return "chars/4"
+}
+
+func (Heuristic) CountTokens(text string) int {
+	return (utf8.RuneCountInString(text) + charsPerToken - 1) / charsPerToken
+}
+
+func New(name, bpeFile string) (Tokenizer, error) {
+	switch name {
+	case "", "chars":
+		return Heuristic{}, nil
+	case "bpe":
+		if bpeFile == "" {
+			return LoadEmbedded(DefaultEncoding)
+		}
+		return LoadBPEFile(bpeFile)
+	default:
+		return nil, fmt.Errorf("unknown tokenizer %q (expected chars or bpe)", name)
+	}
+}
@@ -0,0 +1,113 @@
+package tokenizer
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestHeuristic(t *testing.T) {
+	tests := map[string]int{
+		"":          0,
+		"abc":       1,
+		"abcd":      1,
+		"abcde":     2,
+		"héllo wör": 3,
+	}
+
+	for text, expected := range tests {
+		if actual := (Heuristic{}).CountTokens(text); actual != expected {
+			t.Errorf("CountTokens(%q): expected %d, got %d", text, expected, actual)
+		}
+	}
+}
+
+func rankTable(tokens ...string) string {
+	var table strings.Builder
+	for rank, token := range tokens {
+		fmt.Fprintf(&table, "%s %d\n", base64.StdEncoding.EncodeToString([]byte(token)), rank)
+	}
+	return table.String()
+}
+
+func TestBPE(t *testing.T) {
+	table := rankTable("h", "e", "l", "o", " ", "w", "r", "d", "he", "ll", "hell", "hello", " w", "or", " wor", "\xc3", "\xa9", "é", "hé")
+
+	bpe, err := LoadBPE(strings.NewReader(table))
+	if err != nil {
+		t.Fatalf("failed to load ranks: %v", err)
+	}
+
+	tests := []struct {
+		text     string
+		expected int
+	}{
+		{text: "", expected: 0},
+		{text: "hello", expected: 1},
+		{text: "hello world", expected: 4},
+		{text: "hell", expected: 1},
+		{text: "held", expected: 3},
+		{text: "xyz", expected: 3},
+		{text: "héllo", expected: 3},
+		{text: "hü", expected: 3},
+	}
+
+	for _, tt := range tests {
+		if actual := bpe.CountTokens(tt.text); actual != tt.expected {
+			t.Errorf("CountTokens(%q): expected %d, got %d", tt.text, tt.expected, actual)
+		}
+	}
+}
+
+func TestLoadBPEErrors(t *testing.T) {
+	tests := map[string]string{
+		"missing rank":  "aGVsbG8=\n",
+		"invalid token": "!!! 1\n",
+		"invalid rank":  "aGVsbG8= one\n",
+		"empty table":   "\n",
+	}
+
+	for name, table := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, err := LoadBPE(strings.NewReader(table)); err == nil {
+				t.Error("expected error but got none")
+			}
+		})
+	}
+}
+
+func TestNew(t *testing.T) {
+	if tok, err := New("", ""); err != nil || tok.Name() != "chars/4" {
+		t.Errorf("expected heuristic tokenizer by default, got %v, %v", tok, err)
+	}
+	if _, err := New("unknown", ""); err == nil {
+		t.Error("expected error for unknown tokenizer")
+	}
+}
+
+func TestLoadEmbedded(t *testing.T) {
+	_, err := embeddedRanks.Open("ranks/" + DefaultEncoding + ".tiktoken.gz")
+	embedded := err == nil
+
+	tok, err := New("bpe", "")
+	if !embedded {
+		if err == nil || !strings.Contains(err.Error(), "go generate") {
+			t.Errorf("expected an error pointing to go generate, got %v", err)
+		}
+		t.Skip("no embedded rank table in this build")
+	}
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if tok.Name() != DefaultEncoding {
+		t.Errorf("expected %s, got %s", DefaultEncoding, tok.Name())
+	}
+	if count := tok.CountTokens("hello world"); count != 2 {
+		t.Errorf("expected 2 tokens for \"hello world\", got %d", count)
+	}
+
+	if _, err := LoadEmbedded("unknown"); err == nil {
+		t.Error("expected error for an encoding that is not embedded")
+	}
+}
@@ -0,0 +1,217 @@
+// Package fusectx resolves hierarchical text files into a single context.
+package fusectx
+
+import (
+	"fmt"
+	"io/fs"
+	"path/filepath"
+
+	"github.com/hbelmiro/fusectx/internal/resolver"
+	"github.com/hbelmiro/fusectx/internal/tokenizer"
+)
+
+const (
+	FormatText = resolver.FormatText
+	FormatXML  = resolver.FormatXML
+	FormatJSON = resolver.FormatJSON
+)
+
+const (
+	AnnotateHTML    = resolver.AnnotateHTML
+	AnnotateXML     = resolver.AnnotateXML
+	AnnotateHeading = resolver.AnnotateHeading
+)
+
+type (
+	Result      = resolver.Result
+	Document    = resolver.Document
+	Segment     = resolver.DocumentSegment
+	Frontmatter = resolver.DocumentFrontmatter
+	XMLTags     = resolver.XMLTags
+	Cut         = resolver.Cut
+	Transform   = resolver.Transform
+	Tokenizer   = tokenizer.Tokenizer
+	Graph       = resolver.Graph
+	GraphNode   = resolver.GraphNode
+	GraphEdge   = resolver.GraphEdge
+)
+
+type (
+	CycleError       = resolver.CycleError
+	MissingFileError = resolver.MissingFileError
+	FrontmatterError = resolver.FrontmatterError
+	ValidationError  = resolver.ValidationError
+	Problem          = resolver.Problem
+)
+
+const (
+	ProblemCycle       = resolver.ProblemCycle
+	ProblemMissingFile = resolver.ProblemMissingFile
+	ProblemFrontmatter = resolver.ProblemFrontmatter
+	ProblemError       = resolver.ProblemError
+)
+
+type Dependency struct {
+	Path    string
+	File    string
+	Content string
+}
+
+type Resolver struct {
+	baseDir string
+	opts    resolver.Options
+}
+
+type Option func(*Resolver)
+
+func New(options ...Option) *Resolver {
+	r := &Resolver{}
+	for _, option := range options {
+		option(r)
+	}
+	return r
+}
+
+func WithBaseDir(dir string) Option {
+	return func(r *Resolver) {
+		r.baseDir = dir
+	}
+}
+
+func WithFS(fsys fs.FS) Option {
+	return func(r *Resolver) {
+		r.opts.FS = fsys
+	}
+}
+
+func WithDedup(enabled bool) Option {
+	return func(r *Resolver) {
+		r.opts.AllowDuplicates = !enabled
+	}
+}
+
+func WithMaxDepth(depth int) Option {
+	return func(r *Resolver) {
+		r.opts.MaxDepth = depth
+	}
+}
+
+func WithParseCache(enabled bool) Option {
+	return func(r *Resolver) {
+		r.opts.Cache = nil
+		if enabled {
+			r.opts.Cache = resolver.NewParseCache()
+		}
+	}
+}
+
+func WithTransform(transform Transform) Option {
+	return func(r *Resolver) {
+		r.opts.Transforms = append(r.opts.Transforms, transform)
+	}
+}
+
+func WithProfile(profile string) Option {
+	return func(r *Resolver) {
+		r.opts.Profile = profile
+	}
+}
+
+func WithTags(tags ...string) Option {
+	return func(r *Resolver) {
+		r.opts.Tags = append(r.opts.Tags, tags...)
+	}
+}
+
+func WithVars(vars map[string]string) Option {
+	return func(r *Resolver) {
+		if r.opts.Vars == nil {
+			r.opts.Vars = make(map[string]string)
+		}
+		for key, value := range vars {
+			r.opts.Vars[key] = value
+		}
+	}
+}
+
+func WithMaxTokens(maxTokens int) Option {
+	return func(r *Resolver) {
+		r.opts.MaxTokens = maxTokens
+	}
+}
+
+func WithStrict(strict bool) Option {
+	return func(r *Resolver) {
+		r.opts.Strict = strict
+	}
+}
+
+func WithTokenizer(tok Tokenizer) Option {
+	return func(r *Resolver) {
+		r.opts.Tokenizer = tok
+	}
+}
+
+func WithFormat(format string) Option {
+	return func(r *Resolver) {
+		r.opts.Format = format
+	}
+}
+
+func WithAnnotate(style string) Option {
+	return func(r *Resolver) {
+		r.opts.Annotate = style
+	}
+}
+
+func NewTokenizer(name, bpeFile string) (Tokenizer, error) {
+	return tokenizer.New(name, bpeFile)
+}
+
+func (r *Resolver) Resolve(path string) (*Result, error) {
+	return resolver.ResolveWithOptions(r.path(path), r.opts)
+}
+
+func (r *Resolver) Validate(path string) error {
+	return resolver.ValidateChainWithOptions(r.path(path), r.opts)
+}
+
+func (r *Resolver) Chain(path string) ([]Dependency, error) {
+	chain, err := resolver.GetDependencyChainWithOptions(r.path(path), r.opts)
+	if err != nil {
+		return nil, err
+	}
+
+	dependencies := make([]Dependency, len(chain))
+	for i, entry := range chain {
+		content, err := r.Content(entry)
+		if err != nil {
+			return nil, err
+		}
+		dependencies[i] = Dependency{Path: entry, File: resolver.EntryFile(entry), Content: content}
+	}
+	return dependencies, nil
+}
+
+func (r *Resolver) Content(entry string) (string, error) {
+	content, err := resolver.EntryContentWithOptions(entry, r.opts)
+	if err != nil {
+		return "", fmt.Errorf("error reading %s: %w", entry, err)
+	}
+	return content, nil
+}
+
+func (r *Resolver) Graph(paths ...string) (*Graph, error) {
+	roots := make([]string, len(paths))
+	for i, path := range paths {
+		roots[i] = r.path(path)
+	}
+	return resolver.GetDependencyGraph(roots, r.opts)
+}
+
+func (r *Resolver) path(path string) string {
+	if r.baseDir == "" || filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(r.baseDir, path)
+}
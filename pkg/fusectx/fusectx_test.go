@@ -0,0 +1,209 @@
+package fusectx
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestResolver(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "fusectx-api-test")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	files := map[string]string{
+		"common.md": "# Common",
+		"base.md": `---
+extends: common.md
+---
+# Base for {{ .team }}`,
+		"main.md": `---
+extends: base.md
+includes:
+  - common.md
+vars:
+  team: platform
+---
+# Main`,
+	}
+
+	for filename, content := range files {
+		err := os.WriteFile(filepath.Join(tmpDir, filename), []byte(content), 0644)
+		if err != nil {
+			t.Fatalf("failed to write test file %s: %v", filename, err)
+		}
+	}
+
+	tests := []struct {
+		name     string
+		options  []Option
+		expected string
+		hasError bool
+	}{
+		{
+			name:     "defaults",
+			options:  []Option{WithBaseDir(tmpDir)},
+			expected: "# Common\n\n# Base for platform\n\n# Main",
+		},
+		{
+			name:     "without dedup",
+			options:  []Option{WithBaseDir(tmpDir), WithDedup(false)},
+			expected: "# Common\n\n# Base for platform\n\n# Common\n\n# Main",
+		},
+		{
+			name:     "vars",
+			options:  []Option{WithBaseDir(tmpDir), WithVars(map[string]string{"team": "billing"})},
+			expected: "# Common\n\n# Base for billing\n\n# Main",
+		},
+		{
+			name: "transform",
+			options: []Option{WithBaseDir(tmpDir), WithTransform(func(path, content string) (string, error) {
+				return strings.TrimPrefix(content, "# "), nil
+			})},
+			expected: "Common\n\nBase for platform\n\nMain",
+		},
+		{
+			name:     "parse cache",
+			options:  []Option{WithBaseDir(tmpDir), WithParseCache(true)},
+			expected: "# Common\n\n# Base for platform\n\n# Main",
+		},
+		{
+			name:     "max depth",
+			options:  []Option{WithBaseDir(tmpDir), WithMaxDepth(1)},
+			hasError: true,
+		},
+		{
+			name:     "relative to working directory without base dir",
+			hasError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := New(tt.options...).Resolve("main.md")
+			if tt.hasError {
+				if err == nil {
+					t.Error("expected error but got none")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if result.Content != tt.expected {
+				t.Errorf("expected:\n%s\n\ngot:\n%s", tt.expected, result.Content)
+			}
+			if result.Document.Content != tt.expected {
+				t.Errorf("expected document content:\n%s\n\ngot:\n%s", tt.expected, result.Document.Content)
+			}
+		})
+	}
+}
+
+func TestResolverDocument(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "fusectx-api-test")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	err = os.WriteFile(filepath.Join(tmpDir, "base.md"), []byte("# Base"), 0644)
+	if err != nil {
+		t.Fatalf("failed to write test file: %v", err)
+	}
+	err = os.WriteFile(filepath.Join(tmpDir, "main.md"), []byte("---\nextends: base.md\n---\n# Main"), 0644)
+	if err != nil {
+		t.Fatalf("failed to write test file: %v", err)
+	}
+
+	r := New(WithBaseDir(tmpDir), WithFormat(FormatXML))
+
+	result, err := r.Resolve("main.md")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.HasPrefix(result.Content, "<documents>") {
+		t.Errorf("expected XML output, got:\n%s", result.Content)
+	}
+
+	segments := result.Document.Segments
+	if len(segments) != 2 || segments[0].Path != "base.md" || segments[0].Relation != "extends" || segments[1].Relation != "self" {
+		t.Errorf("unexpected segments: %+v", segments)
+	}
+
+	if err := r.Validate("main.md"); err != nil {
+		t.Errorf("unexpected validation error: %v", err)
+	}
+	if err := r.Validate("missing.md"); err == nil {
+		t.Error("expected validation error for missing file")
+	}
+
+	chain, err := r.Chain("main.md")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(chain) != 2 || filepath.Base(chain[0].Path) != "base.md" || chain[0].Content != "# Base" || chain[1].Content != "# Main" {
+		t.Errorf("unexpected chain: %+v", chain)
+	}
+}
+
+func TestResolverFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"shared/base.md":    {Data: []byte("# Base")},
+		"teams/billing.md":  {Data: []byte("---\nextends: ../shared/base.md\nincludes:\n  - /shared/rules/*.md\n---\n# Billing")},
+		"shared/rules/a.md": {Data: []byte("# Rule A")},
+		"escape.md":         {Data: []byte("---\nextends: ../outside.md\n---\n# Escape")},
+	}
+
+	r := New(WithFS(fsys), WithBaseDir("teams"))
+
+	result, err := r.Resolve("billing.md")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := "# Base\n\n# Rule A\n\n# Billing"
+	if result.Content != expected {
+		t.Errorf("expected:\n%s\n\ngot:\n%s", expected, result.Content)
+	}
+
+	chain, err := r.Chain("billing.md")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var paths []string
+	for _, dependency := range chain {
+		paths = append(paths, dependency.Path)
+	}
+	if strings.Join(paths, ",") != "shared/base.md,teams/billing.md,shared/rules/a.md" {
+		t.Errorf("unexpected chain: %v", paths)
+	}
+
+	if err := New(WithFS(fsys)).Validate("escape.md"); err == nil {
+		t.Error("expected error for a path outside the filesystem")
+	}
+}
+
+func TestResolverErrors(t *testing.T) {
+	fsys := fstest.MapFS{
+		"main.md":  {Data: []byte("---\nextends: base.md\n---\n# Main")},
+		"base.md":  {Data: []byte("---\nincludes:\n  - main.md\n---\n# Base")},
+		"other.md": {Data: []byte("---\nincludes:\n  - gone.md\n---\n# Other")},
+	}
+	r := New(WithFS(fsys))
+
+	var cycleErr *CycleError
+	if err := r.Validate("main.md"); !errors.As(err, &cycleErr) || len(cycleErr.Cycle) != 3 {
+		t.Errorf("expected a CycleError, got %v", err)
+	}
+
+	var missingErr *MissingFileError
+	if _, err := r.Chain("other.md"); !errors.As(err, &missingErr) || missingErr.Referrer != "other.md" || missingErr.Line != 3 {
+		t.Errorf("expected a MissingFileError at other.md:3, got %v", err)
+	}
+}